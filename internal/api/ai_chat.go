@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"cinema/internal/models"
+	"cinema/internal/service"
 )
 
 const cinemaGoSystemPrompt = `
@@ -27,7 +28,7 @@ STRICT SCOPE RULES:
 YOU CAN:
 - Recommend movies ONLY from available_movies.
 - Suggest sessions ONLY from sessions list.
-- Explain how to book and student discount (20%) if present in context.
+- Explain how to book and the discounts/surcharges listed in pricing_rules.
 
 STYLE:
 - Short, friendly, actionable.
@@ -52,7 +53,7 @@ type openAIResponse struct {
 	} `json:"output"`
 }
 
-func AIChatHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AIChatHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -75,7 +76,7 @@ func AIChatHandler(w http.ResponseWriter, r *http.Request) {
 		model = "gpt-4.1-mini"
 	}
 
-	ctxText, err := buildCinemaGoContext()
+	ctxText, err := buildCinemaGoContext(h.Repositories)
 	if err != nil {
 		http.Error(w, "context error: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -159,8 +160,12 @@ func callOpenAIResponses(apiKey, model, userPrompt string) (string, error) {
 	return s, nil
 }
 
-func buildCinemaGoContext() (string, error) {
-	sessions, err := models.GetAllSessionsMongo()
+func buildCinemaGoContext(repos models.Repositories) (string, error) {
+	sessions, err := repos.Sessions.GetAll()
+	if err != nil {
+		return "", err
+	}
+	rules, err := models.ActivePricingRules(repos.Pricing)
 	if err != nil {
 		return "", err
 	}
@@ -202,11 +207,20 @@ func buildCinemaGoContext() (string, error) {
 		b.WriteString(fmt.Sprintf("  hall: %q\n", s.Hall))
 		b.WriteString(fmt.Sprintf("  datetime: %q\n", s.StartTime.Format(time.RFC3339)))
 		b.WriteString(fmt.Sprintf("  base_price: %v\n", s.BasePrice))
+		if s.Format != "" {
+			b.WriteString(fmt.Sprintf("  format: %q\n", s.Format))
+		}
+		if s.MinAge > 0 {
+			b.WriteString(fmt.Sprintf("  min_age: %d\n", s.MinAge))
+		}
 		b.WriteString(fmt.Sprintf("  available_seats: %v\n", len(s.AvailableSeats)))
 	}
 
-	b.WriteString("discounts:\n- student_discount: 20%\n")
-	b.WriteString("booking_rules:\n- age_limit: 18+\n- seat_required: yes\n")
+	b.WriteString("pricing_rules:\n")
+	for _, r := range rules {
+		b.WriteString("- " + service.DescribePricingRule(r) + "\n")
+	}
+	b.WriteString("booking_rules:\n- age_limit: per session (min_age)\n- seat_required: yes\n")
 
 	return b.String(), nil
 }
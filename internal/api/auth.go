@@ -4,10 +4,13 @@ import (
 	"cinema/internal/models"
 	"cinema/internal/service"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 )
 
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, 405, map[string]string{"error": "POST only"})
 		return
@@ -24,6 +27,20 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	input.Email = service.NormalizeEmail(input.Email)
+	input.Username = strings.TrimSpace(input.Username)
+	if err := service.ValidateEmail(input.Email); err != nil {
+		writeJSON(w, 400, map[string]string{"error": err.Error()})
+		return
+	}
+	if err := service.ValidatePassword(input.Password); err != nil {
+		writeJSON(w, 400, map[string]string{"error": err.Error()})
+		return
+	}
+	if input.Username == "" {
+		input.Username = input.Email[:strings.Index(input.Email, "@")]
+	}
+
 	hash, err := service.HashPassword(input.Password)
 	if err != nil {
 		writeJSON(w, 500, map[string]string{"error": "password error"})
@@ -37,15 +54,21 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		Role:     "user",
 	}
 
-	if err := models.CreateUser(user); err != nil {
+	if err := h.Users.Create(user); err != nil {
 		writeJSON(w, 400, map[string]string{"error": err.Error()})
 		return
 	}
+	if err := models.SendEmailVerification(h.Repositories, user); err != nil {
+		log.Println("[AUTH] verification email failed:", err)
+	}
 
-	writeJSON(w, 201, map[string]string{"status": "registered"})
+	writeJSON(w, 201, map[string]any{
+		"status":                "registered",
+		"verification_required": service.RequireEmailVerification(),
+	})
 }
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, 405, map[string]string{"error": "POST only"})
 		return
@@ -61,26 +84,240 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, ok, err := models.GetUserByEmail(input.Email)
-	if err != nil || !ok {
+	email := service.NormalizeEmail(input.Email)
+	ip := service.ClientIP(r)
+	until, err := models.LoginLockedUntil(h.Repositories, email, ip)
+	if err != nil {
+		log.Println("[AUTH] lockout check failed:", err)
+	}
+	if !until.IsZero() {
+		writeLocked(w, until)
+		return
+	}
+
+	user, ok, err := h.Users.GetByEmail(email)
+	if err != nil {
 		writeJSON(w, 401, map[string]string{"error": "invalid credentials"})
 		return
 	}
 
-	if err := service.CheckPassword(user.Password, input.Password); err != nil {
+	if !ok || service.CheckPassword(user.Password, input.Password) != nil {
+		if err := models.RecordLoginFailure(h.Repositories, email, ip, ok); err != nil {
+			log.Println("[AUTH] recording failed login:", err)
+		}
 		writeJSON(w, 401, map[string]string{"error": "invalid credentials"})
 		return
 	}
+	if err := models.RecordLoginSuccess(h.Repositories, email); err != nil {
+		log.Println("[AUTH] resetting login attempts:", err)
+	}
+
+	if service.RequireEmailVerification() && !user.EmailVerified {
+		writeJSON(w, 403, map[string]string{"error": "email not verified"})
+		return
+	}
+
+	if user.TwoFactor.Enabled {
+		mfaToken, err := models.StartMFALogin(h.Repositories, user)
+		if err != nil {
+			writeJSON(w, 500, map[string]string{"error": "could not start two-factor login"})
+			return
+		}
+		writeJSON(w, 200, map[string]any{
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		})
+		return
+	}
 
-	token, err := service.GenerateJWT(user.Email, user.Username, user.Role)
+	tokens, err := models.IssueAuthTokens(h.Repositories, user, false)
 	if err != nil {
 		writeJSON(w, 500, map[string]string{"error": "could not generate token"})
 		return
 	}
 
-	writeJSON(w, 200, map[string]string{
-		"token":    token,
-		"role":     user.Role,
-		"username": user.Username,
+	writeJSON(w, 200, map[string]any{
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"role":               user.Role,
+		"username":           user.Username,
+		"mfa_setup_required": service.TwoFactorRequired(user.Role),
+	})
+}
+
+func (h *Handler) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.RefreshToken == "" {
+		writeJSON(w, 400, map[string]string{"error": "refresh_token is required"})
+		return
+	}
+
+	user, tokens, err := models.RotateRefreshToken(h.Repositories, input.RefreshToken)
+	if err != nil {
+		if errors.Is(err, models.ErrRefreshTokenInvalid) || errors.Is(err, models.ErrRefreshTokenReused) {
+			writeJSON(w, 401, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, 500, map[string]string{"error": "could not refresh token"})
+		return
+	}
+
+	writeJSON(w, 200, map[string]any{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"role":          user.Role,
+		"username":      user.Username,
 	})
 }
+
+func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&input)
+
+	claims, _ := r.Context().Value(service.ClaimsKey).(*service.JWTClaim)
+	if err := models.RevokeAccessToken(h.Repositories, claims); err != nil {
+		writeJSON(w, 500, map[string]string{"error": "could not revoke token"})
+		return
+	}
+	if input.RefreshToken != "" && claims != nil {
+		if err := models.RevokeRefreshToken(h.Repositories, input.RefreshToken, claims.Email); err != nil {
+			writeJSON(w, 500, map[string]string{"error": "could not revoke token"})
+			return
+		}
+	}
+
+	writeJSON(w, 200, map[string]string{"status": "logged out"})
+}
+
+func (h *Handler) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	email, _ := r.Context().Value(service.EmailKey).(string)
+	if err := models.RevokeAllSessions(h.Repositories, email); err != nil {
+		writeJSON(w, 500, map[string]string{"error": "could not revoke sessions"})
+		return
+	}
+
+	writeJSON(w, 200, map[string]string{"status": "logged out everywhere"})
+}
+
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var token string
+	switch r.Method {
+	case http.MethodGet:
+		token = r.URL.Query().Get("token")
+	case http.MethodPost:
+		var input struct {
+			Token string `json:"token"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&input)
+		token = input.Token
+	default:
+		writeJSON(w, 405, map[string]string{"error": "GET or POST only"})
+		return
+	}
+
+	_, err := models.VerifyEmail(h.Repositories, token)
+	if r.Method == http.MethodGet {
+		status := "verified"
+		if err != nil {
+			status = "invalid"
+		}
+		http.Redirect(w, r, "/pages/auth.html?email="+status, http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrActionTokenInvalid) {
+			writeJSON(w, 400, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, 500, map[string]string{"error": "could not verify email"})
+		return
+	}
+	writeJSON(w, 200, map[string]string{"status": "verified"})
+}
+
+func (h *Handler) ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&input)
+
+	if user, ok, err := h.Users.GetByEmail(service.NormalizeEmail(input.Email)); err == nil && ok {
+		if err := models.SendEmailVerification(h.Repositories, user); err != nil {
+			log.Println("[AUTH] verification email failed:", err)
+		}
+	}
+	writeJSON(w, 200, map[string]string{"status": "if the account exists, a verification email has been sent"})
+}
+
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&input)
+
+	if err := models.RequestPasswordReset(h.Repositories, service.NormalizeEmail(input.Email)); err != nil {
+		log.Println("[AUTH] password reset failed:", err)
+	}
+	writeJSON(w, 200, map[string]string{"status": "if the account exists, a reset link has been sent"})
+}
+
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, 400, map[string]string{"error": "invalid json"})
+		return
+	}
+	if err := service.ValidatePassword(input.Password); err != nil {
+		writeJSON(w, 400, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err := models.ResetPassword(h.Repositories, input.Token, input.Password); err != nil {
+		if errors.Is(err, service.ErrActionTokenInvalid) {
+			writeJSON(w, 400, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, 500, map[string]string{"error": "could not reset password"})
+		return
+	}
+	writeJSON(w, 200, map[string]string{"status": "password updated"})
+}
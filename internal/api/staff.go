@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"cinema/internal/models"
+	"cinema/internal/service"
+)
+
+func (h *Handler) UsersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, 405, map[string]string{"error": "GET only"})
+		return
+	}
+
+	users, err := h.Users.List()
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, 200, users)
+}
+
+func (h *Handler) RolesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, 405, map[string]string{"error": "GET only"})
+		return
+	}
+
+	roles := map[string][]service.Permission{}
+	for _, role := range []string{service.RoleSuperAdmin, service.RoleCinemaManager, service.RoleCashier, service.RoleUsher, service.RoleUser} {
+		roles[role] = service.RolePermissions(role)
+	}
+	writeJSON(w, 200, roles)
+}
+
+func (h *Handler) AssignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	var input struct {
+		Email   string   `json:"email"`
+		Role    string   `json:"role"`
+		Cinemas []string `json:"cinemas"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, 400, map[string]string{"error": "invalid json"})
+		return
+	}
+
+	email := service.NormalizeEmail(input.Email)
+	if self, _ := r.Context().Value(service.EmailKey).(string); strings.EqualFold(self, email) && service.NormalizeRole(input.Role) != service.RoleSuperAdmin {
+		writeJSON(w, 400, map[string]string{"error": "cannot downgrade your own role"})
+		return
+	}
+
+	u, err := models.AssignRole(h.Repositories, email, input.Role, input.Cinemas)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			writeJSON(w, 404, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, 400, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, 200, u)
+}
+
+func (h *Handler) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Email == "" {
+		writeJSON(w, 400, map[string]string{"error": "email required"})
+		return
+	}
+
+	if err := models.UnlockAccount(h.Repositories, service.NormalizeEmail(input.Email)); err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, 200, map[string]string{"status": "unlocked"})
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"cinema/internal/models"
+	"cinema/internal/service"
+)
+
+func (h *Handler) currentUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	email, _ := r.Context().Value(service.EmailKey).(string)
+	u, ok, err := h.Users.GetByEmail(email)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return models.User{}, false
+	}
+	if !ok {
+		writeJSON(w, 404, map[string]string{"error": "user not found"})
+		return models.User{}, false
+	}
+	return u, true
+}
+
+func decodeCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var input struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Code == "" {
+		writeJSON(w, 400, map[string]string{"error": "code required"})
+		return "", false
+	}
+	return input.Code, true
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrTwoFactorInvalid):
+		writeJSON(w, 401, map[string]string{"error": err.Error()})
+	case errors.Is(err, models.ErrTwoFactorNotEnrolled), errors.Is(err, models.ErrTwoFactorAlreadyEnabled), errors.Is(err, models.ErrTwoFactorMandatory):
+		writeJSON(w, 409, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+	}
+}
+
+func (h *Handler) TwoFactorSetupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	secret, uri, err := models.BeginTwoFactorSetup(h.Repositories, u)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]string{
+		"secret":      secret,
+		"otpauth_uri": uri,
+	})
+}
+
+func (h *Handler) TwoFactorEnableHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := models.EnableTwoFactor(h.Repositories, u, code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	tokens, err := models.IssueAuthTokens(h.Repositories, u, true)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": "could not generate token"})
+		return
+	}
+	writeJSON(w, 200, map[string]any{
+		"recovery_codes": codes,
+		"token":          tokens.AccessToken,
+		"refresh_token":  tokens.RefreshToken,
+		"expires_in":     tokens.ExpiresIn,
+	})
+}
+
+func (h *Handler) TwoFactorDisableHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	if err := models.DisableTwoFactor(h.Repositories, u, code); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]string{"status": "two-factor authentication disabled"})
+}
+
+func (h *Handler) RecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+	u, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	code, ok := decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := models.RegenerateRecoveryCodes(h.Repositories, u, code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	writeJSON(w, 200, map[string]any{"recovery_codes": codes})
+}
+
+func (h *Handler) TwoFactorVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, 405, map[string]string{"error": "POST only"})
+		return
+	}
+
+	var input struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.MFAToken == "" || input.Code == "" {
+		writeJSON(w, 400, map[string]string{"error": "mfa_token and code required"})
+		return
+	}
+
+	claims, err := service.ParseActionToken(input.MFAToken, service.PurposeLoginMFA)
+	if err != nil {
+		writeJSON(w, 401, map[string]string{"error": err.Error()})
+		return
+	}
+	ip := service.ClientIP(r)
+	until, err := models.LoginLockedUntil(h.Repositories, claims.Email, ip)
+	if err != nil {
+		log.Println("[AUTH] lockout check failed:", err)
+	}
+	if !until.IsZero() {
+		writeLocked(w, until)
+		return
+	}
+
+	user, tokens, err := models.CompleteMFALogin(h.Repositories, input.MFAToken, input.Code)
+	if err != nil {
+		if errors.Is(err, models.ErrTwoFactorInvalid) {
+			if err := models.RecordLoginFailure(h.Repositories, claims.Email, ip, true); err != nil {
+				log.Println("[AUTH] recording failed login:", err)
+			}
+		}
+		if errors.Is(err, service.ErrActionTokenInvalid) {
+			writeJSON(w, 401, map[string]string{"error": err.Error()})
+			return
+		}
+		writeTwoFactorError(w, err)
+		return
+	}
+	if err := models.RecordLoginSuccess(h.Repositories, user.Email); err != nil {
+		log.Println("[AUTH] resetting login attempts:", err)
+	}
+
+	writeJSON(w, 200, map[string]any{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"role":          user.Role,
+		"username":      user.Username,
+	})
+}
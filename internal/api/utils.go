@@ -2,11 +2,32 @@ package api
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
+
+	"cinema/internal/models"
 )
 
+type Handler struct {
+	models.Repositories
+}
+
+func New(repos models.Repositories) *Handler {
+	return &Handler{Repositories: repos}
+}
+
 func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(payload)
 }
+
+func writeLocked(w http.ResponseWriter, until time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(until).Seconds()))))
+	writeJSON(w, 429, map[string]any{
+		"error":        "too many failed login attempts, try again later",
+		"locked_until": until,
+	})
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"cinema/internal/service"
+)
+
+type ActionToken struct {
+	JTI       string    `bson:"jti" json:"-"`
+	Email     string    `bson:"email" json:"email"`
+	Purpose   string    `bson:"purpose" json:"purpose"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+	UsedAt    time.Time `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+func issueActionToken(repos Repositories, email, purpose string, ttl time.Duration) (string, error) {
+	token, claims, err := service.GenerateActionToken(email, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+	err = repos.ActionTokens.Create(ActionToken{
+		JTI:       claims.Id,
+		Email:     email,
+		Purpose:   purpose,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	})
+	return token, err
+}
+
+func consumeActionToken(repos Repositories, token, purpose string) (string, error) {
+	claims, err := service.ParseActionToken(token, purpose)
+	if err != nil {
+		return "", err
+	}
+	ok, err := repos.ActionTokens.Consume(claims.Id, purpose)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", service.ErrActionTokenInvalid
+	}
+	return claims.Email, nil
+}
+
+func SendEmailVerification(repos Repositories, u User) error {
+	if u.EmailVerified {
+		return nil
+	}
+	token, err := issueActionToken(repos, u.Email, service.PurposeVerifyEmail, service.VerifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	service.SendVerificationEmail(u.Email, token)
+	return nil
+}
+
+func VerifyEmail(repos Repositories, token string) (string, error) {
+	email, err := consumeActionToken(repos, token, service.PurposeVerifyEmail)
+	if err != nil {
+		return "", err
+	}
+	return email, repos.Users.MarkEmailVerified(email)
+}
+
+func RequestPasswordReset(repos Repositories, email string) error {
+	u, ok, err := repos.Users.GetByEmail(email)
+	if err != nil || !ok {
+		return err
+	}
+	token, err := issueActionToken(repos, u.Email, service.PurposePasswordReset, service.PasswordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	service.SendPasswordResetEmail(u.Email, token)
+	return nil
+}
+
+func ResetPassword(repos Repositories, token, password string) error {
+	email, err := consumeActionToken(repos, token, service.PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	hash, err := service.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := repos.Users.UpdatePassword(email, hash); err != nil {
+		return err
+	}
+	_ = repos.Users.MarkEmailVerified(email)
+	return RevokeAllSessions(repos, email)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type mongoActionTokenRepository struct{}
+
+func NewMongoActionTokenRepository() ActionTokenRepository {
+	return &mongoActionTokenRepository{}
+}
+
+func (r *mongoActionTokenRepository) Create(t ActionToken) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.CreatedAt = time.Now()
+	_, err := service.ActionTokensCollection().InsertOne(ctx, t)
+	return err
+}
+
+func (r *mongoActionTokenRepository) Consume(jti string, purpose string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"jti":        jti,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	res, err := service.ActionTokensCollection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"used_at": now}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
@@ -1,13 +1,202 @@
 package models
 
-var AllowedCinemas = map[string]bool{
-	"Chaplin MEGA Silk Way":    true,
-	"Chaplin Khan Shatyr":      true,
-	"Arman Asia Park":          true,
-	"Kinopark 6 Keruencity":    true,
-	"Kinopark 8 IMAX Saryarqa": true,
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"cinema/internal/service"
+)
+
+var (
+	ErrCinemaNotFound = errors.New("cinema not found")
+	ErrCinemaExists   = errors.New("cinema with this name already exists")
+	ErrCinemaInUse    = errors.New("cinema still has halls or upcoming sessions")
+	ErrCinemaLocked   = errors.New("city and timezone cannot change while the cinema has upcoming sessions")
+)
+
+type Cinema struct {
+	ID        int      `json:"id" bson:"id"`
+	Name      string   `json:"name" bson:"name"`
+	City      string   `json:"city" bson:"city"`
+	Address   string   `json:"address" bson:"address"`
+	Latitude  float64  `json:"latitude" bson:"latitude"`
+	Longitude float64  `json:"longitude" bson:"longitude"`
+	Timezone  string   `json:"timezone" bson:"timezone"`
+	Phone     string   `json:"phone,omitempty" bson:"phone,omitempty"`
+	Amenities []string `json:"amenities,omitempty" bson:"amenities,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+type CinemaDetails struct {
+	Cinema
+	Halls []Hall `json:"halls"`
+}
+
+var defaultCinemas = []Cinema{
+	{Name: "Chaplin MEGA Silk Way", City: "Astana", Address: "Kabanbay Batyr Ave 62", Latitude: 51.0889, Longitude: 71.4086, Amenities: []string{"parking", "food_court", "wheelchair_access"}},
+	{Name: "Chaplin Khan Shatyr", City: "Astana", Address: "Turan Ave 37", Latitude: 51.1326, Longitude: 71.4040, Amenities: []string{"parking", "food_court", "vip_hall"}},
+	{Name: "Arman Asia Park", City: "Astana", Address: "Kabanbay Batyr Ave 21", Latitude: 51.1259, Longitude: 71.4437, Amenities: []string{"parking", "food_court"}},
+	{Name: "Kinopark 6 Keruencity", City: "Astana", Address: "Dostyk St 9", Latitude: 51.1283, Longitude: 71.4302, Amenities: []string{"food_court", "wheelchair_access"}},
+	{Name: "Kinopark 8 IMAX Saryarqa", City: "Astana", Address: "Turan Ave 24", Latitude: 51.1339, Longitude: 71.4069, Amenities: []string{"imax", "parking", "food_court"}},
+}
+
+func (c *Cinema) Normalize() {
+	c.Name = strings.TrimSpace(c.Name)
+	c.City = strings.TrimSpace(c.City)
+	c.Address = strings.TrimSpace(c.Address)
+	c.Timezone = strings.TrimSpace(c.Timezone)
+	if c.Timezone == "" {
+		c.Timezone = service.DefaultTimezone
+	}
+	seen := map[string]bool{}
+	amenities := make([]string, 0, len(c.Amenities))
+	for _, a := range c.Amenities {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a != "" && !seen[a] {
+			seen[a] = true
+			amenities = append(amenities, a)
+		}
+	}
+	c.Amenities = amenities
+}
+
+func (c Cinema) Validate() error {
+	if c.Name == "" {
+		return errors.New("cinema name is required")
+	}
+	if c.City == "" || c.Address == "" {
+		return errors.New("city and address are required")
+	}
+	if c.Latitude < -90 || c.Latitude > 90 || c.Longitude < -180 || c.Longitude > 180 {
+		return errors.New("coordinates are out of range")
+	}
+	_, err := service.ParseLocation(c.Timezone)
+	return err
+}
+
+func SeedCinemas(repo CinemaRepository) error {
+	cinemas, err := repo.List("")
+	if err != nil || len(cinemas) > 0 {
+		return err
+	}
+	for _, c := range defaultCinemas {
+		c.Normalize()
+		if _, err := repo.Create(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CinemaExists(repos Repositories, name string) (bool, error) {
+	_, ok, err := repos.Cinemas.GetByName(name)
+	return ok, err
+}
+
+func CreateCinema(repos Repositories, c Cinema) (Cinema, error) {
+	c.Normalize()
+	if err := c.Validate(); err != nil {
+		return Cinema{}, err
+	}
+	if _, ok, err := repos.Cinemas.GetByName(c.Name); err != nil {
+		return Cinema{}, err
+	} else if ok {
+		return Cinema{}, ErrCinemaExists
+	}
+	return repos.Cinemas.Create(c)
+}
+
+func UpdateCinema(repos Repositories, id int, c Cinema) (Cinema, error) {
+	current, ok, err := repos.Cinemas.GetByID(id)
+	if err != nil {
+		return Cinema{}, err
+	}
+	if !ok {
+		return Cinema{}, ErrCinemaNotFound
+	}
+	c.Normalize()
+	if c.Name != current.Name {
+		return Cinema{}, errors.New("cinema name cannot be changed")
+	}
+	if err := c.Validate(); err != nil {
+		return Cinema{}, err
+	}
+	// Sessions and orders keep their own copy of the city and timezone, so
+	// moving a cinema that still has upcoming shows would leave them stale.
+	if c.City != current.City || c.Timezone != current.Timezone {
+		upcoming, err := hasUpcomingSessions(repos, current.Name)
+		if err != nil {
+			return Cinema{}, err
+		}
+		if upcoming {
+			return Cinema{}, ErrCinemaLocked
+		}
+	}
+	c.ID = current.ID
+	c.CreatedAt = current.CreatedAt
+	c.UpdatedAt = time.Now()
+	ok, err = repos.Cinemas.Update(c)
+	if err != nil {
+		return Cinema{}, err
+	}
+	if !ok {
+		return Cinema{}, ErrCinemaNotFound
+	}
+	return c, nil
+}
+
+func DeleteCinema(repos Repositories, id int) error {
+	c, ok, err := repos.Cinemas.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCinemaNotFound
+	}
+	halls, err := repos.Halls.List(c.Name)
+	if err != nil {
+		return err
+	}
+	if len(halls) > 0 {
+		return ErrCinemaInUse
+	}
+	upcoming, err := hasUpcomingSessions(repos, c.Name)
+	if err != nil {
+		return err
+	}
+	if upcoming {
+		return ErrCinemaInUse
+	}
+	ok, err = repos.Cinemas.Delete(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCinemaNotFound
+	}
+	return nil
+}
+
+func CinemaWithHalls(repos Repositories, c Cinema) (CinemaDetails, error) {
+	halls, err := repos.Halls.List(c.Name)
+	if err != nil {
+		return CinemaDetails{}, err
+	}
+	return CinemaDetails{Cinema: c, Halls: halls}, nil
 }
 
-func IsCinemaAllowed(name string) bool {
-	return AllowedCinemas[name]
+func hasUpcomingSessions(repos Repositories, cinema string) (bool, error) {
+	sessions, err := repos.Sessions.Filter(SessionFilter{Cinemas: []string{cinema}})
+	if err != nil {
+		return false, err
+	}
+	for _, s := range sessions {
+		if s.StartTime.After(time.Now()) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
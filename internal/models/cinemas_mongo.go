@@ -0,0 +1,107 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoCinemaRepository struct{}
+
+func NewMongoCinemaRepository() CinemaRepository {
+	return &mongoCinemaRepository{}
+}
+
+func (r *mongoCinemaRepository) Create(c Cinema) (Cinema, error) {
+	id, err := nextID("cinemas")
+	if err != nil {
+		return Cinema{}, err
+	}
+	c.ID = id
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := service.CinemasCollection().InsertOne(ctx, c); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return Cinema{}, ErrCinemaExists
+		}
+		return Cinema{}, err
+	}
+	return c, nil
+}
+
+func (r *mongoCinemaRepository) findOne(filter bson.M) (Cinema, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var c Cinema
+	err := service.CinemasCollection().FindOne(ctx, filter).Decode(&c)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Cinema{}, false, nil
+		}
+		return Cinema{}, false, err
+	}
+	return c, true, nil
+}
+
+func (r *mongoCinemaRepository) GetByID(id int) (Cinema, bool, error) {
+	return r.findOne(bson.M{"id": id})
+}
+
+func (r *mongoCinemaRepository) GetByName(name string) (Cinema, bool, error) {
+	return r.findOne(bson.M{"name": name})
+}
+
+func (r *mongoCinemaRepository) List(city string) ([]Cinema, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if city != "" {
+		filter["city"] = bson.M{"$regex": "^" + regexp.QuoteMeta(city) + "$", "$options": "i"}
+	}
+	cur, err := service.CinemasCollection().Find(ctx, filter, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]Cinema, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoCinemaRepository) Update(c Cinema) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.CinemasCollection().ReplaceOne(ctx, bson.M{"id": c.ID}, c)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (r *mongoCinemaRepository) Delete(id int) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.CinemasCollection().DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount == 1, nil
+}
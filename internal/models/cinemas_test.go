@@ -0,0 +1,50 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUpdateCinemaLocksLocationWithUpcomingSessions(t *testing.T) {
+	repos, hall, movie := newScheduleFixture(t)
+	cinema, _, err := repos.Cinemas.GetByName("Test Cinema")
+	if err != nil {
+		t.Fatalf("get cinema: %v", err)
+	}
+	if _, err := CreateSession(repos, Session{MovieID: movie.ID, HallID: hall.ID, StartTime: time.Now().Add(24 * time.Hour), BasePrice: 2000}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	moved := cinema
+	moved.City = "Astana"
+	moved.Timezone = "Asia/Qostanay"
+	if _, err := UpdateCinema(repos, cinema.ID, moved); !errors.Is(err, ErrCinemaLocked) {
+		t.Fatalf("move with upcoming sessions: err = %v, want ErrCinemaLocked", err)
+	}
+	got, _, _ := repos.Cinemas.GetByID(cinema.ID)
+	if got.City != "Almaty" || got.Timezone != "Asia/Almaty" {
+		t.Fatalf("cinema = %s/%s, want unchanged", got.City, got.Timezone)
+	}
+
+	readdressed := cinema
+	readdressed.Address = "Abay Ave 1"
+	if _, err := UpdateCinema(repos, cinema.ID, readdressed); err != nil {
+		t.Fatalf("address change: %v", err)
+	}
+}
+
+func TestUpdateCinemaMovesWithoutUpcomingSessions(t *testing.T) {
+	repos, _, _ := newScheduleFixture(t)
+	cinema, _, _ := repos.Cinemas.GetByName("Test Cinema")
+
+	cinema.City = "Astana"
+	cinema.Timezone = "Asia/Qostanay"
+	updated, err := UpdateCinema(repos, cinema.ID, cinema)
+	if err != nil {
+		t.Fatalf("UpdateCinema: %v", err)
+	}
+	if updated.City != "Astana" || updated.Timezone != "Asia/Qostanay" {
+		t.Fatalf("cinema = %s/%s, want Astana/Asia/Qostanay", updated.City, updated.Timezone)
+	}
+}
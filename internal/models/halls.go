@@ -0,0 +1,284 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type SeatCategory string
+
+const (
+	SeatStandard   SeatCategory = "standard"
+	SeatVIP        SeatCategory = "vip"
+	SeatLoveSeat   SeatCategory = "love_seat"
+	SeatWheelchair SeatCategory = "wheelchair"
+)
+
+func (c SeatCategory) Valid() bool {
+	switch c {
+	case SeatStandard, SeatVIP, SeatLoveSeat, SeatWheelchair:
+		return true
+	}
+	return false
+}
+
+type Hall struct {
+	ID         int    `json:"id" bson:"id"`
+	CinemaName string `json:"cinema_name" bson:"cinema_name"`
+	Name       string `json:"name" bson:"name"`
+
+	Rows    int   `json:"rows" bson:"rows"`
+	Columns int   `json:"columns" bson:"columns"`
+	Aisles  []int `json:"aisles,omitempty" bson:"aisles,omitempty"`
+
+	RowCategories  map[string]SeatCategory `json:"row_categories,omitempty" bson:"row_categories,omitempty"`
+	SeatCategories map[string]SeatCategory `json:"seat_categories,omitempty" bson:"seat_categories,omitempty"`
+	Blocked        []string                `json:"blocked,omitempty" bson:"blocked,omitempty"`
+}
+
+const (
+	maxHallRows    = 26
+	maxHallColumns = 60
+)
+
+func rowLabel(row int) string {
+	return string(rune('A' + row))
+}
+
+func seatCode(row, col int) string {
+	return rowLabel(row) + strconv.Itoa(col+1)
+}
+
+func (h Hall) hasSeat(code string) bool {
+	for row := 0; row < h.Rows; row++ {
+		for col := 0; col < h.Columns; col++ {
+			if seatCode(row, col) == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func CreateHall(repos Repositories, h Hall) (Hall, error) {
+	if err := h.Validate(); err != nil {
+		return Hall{}, err
+	}
+	exists, err := CinemaExists(repos, h.CinemaName)
+	if err != nil {
+		return Hall{}, err
+	}
+	if !exists {
+		return Hall{}, ErrCinemaNotFound
+	}
+	return repos.Halls.Add(h)
+}
+
+func (h Hall) Validate() error {
+	if h.Name == "" {
+		return errors.New("hall name is required")
+	}
+	if h.Rows < 1 || h.Rows > maxHallRows {
+		return fmt.Errorf("rows must be between 1 and %d", maxHallRows)
+	}
+	if h.Columns < 1 || h.Columns > maxHallColumns {
+		return fmt.Errorf("columns must be between 1 and %d", maxHallColumns)
+	}
+	for _, a := range h.Aisles {
+		if a < 1 || a >= h.Columns {
+			return fmt.Errorf("aisle %d is outside the seat grid", a)
+		}
+	}
+	for row, c := range h.RowCategories {
+		if len(row) != 1 || row[0] < 'A' || int(row[0]-'A') >= h.Rows {
+			return fmt.Errorf("unknown row %q", row)
+		}
+		if !c.Valid() {
+			return fmt.Errorf("unknown seat category %q", c)
+		}
+	}
+	for code, c := range h.SeatCategories {
+		if !h.hasSeat(code) {
+			return fmt.Errorf("unknown seat %q", code)
+		}
+		if !c.Valid() {
+			return fmt.Errorf("unknown seat category %q", c)
+		}
+	}
+	for _, code := range h.Blocked {
+		if !h.hasSeat(code) {
+			return fmt.Errorf("unknown seat %q", code)
+		}
+	}
+	return nil
+}
+
+func (h Hall) Category(code string) SeatCategory {
+	if c, ok := h.SeatCategories[code]; ok {
+		return c
+	}
+	if len(code) > 0 {
+		if c, ok := h.RowCategories[code[:1]]; ok {
+			return c
+		}
+	}
+	return SeatStandard
+}
+
+func (h Hall) IsBlocked(code string) bool {
+	for _, b := range h.Blocked {
+		if b == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (h Hall) SeatCodes() []string {
+	out := make([]string, 0, h.Rows*h.Columns)
+	for row := 0; row < h.Rows; row++ {
+		for col := 0; col < h.Columns; col++ {
+			code := seatCode(row, col)
+			if !h.IsBlocked(code) {
+				out = append(out, code)
+			}
+		}
+	}
+	return out
+}
+
+type SeatStatus string
+
+const (
+	SeatAvailable SeatStatus = "available"
+	SeatHeld      SeatStatus = "held"
+	SeatSold      SeatStatus = "sold"
+	SeatBlocked   SeatStatus = "blocked"
+)
+
+type SeatMapSeat struct {
+	Code     string       `json:"code"`
+	Row      string       `json:"row"`
+	Number   int          `json:"number"`
+	Category SeatCategory `json:"category"`
+	Status   SeatStatus   `json:"status"`
+}
+
+type SeatMap struct {
+	SessionID int             `json:"session_id"`
+	HallID    int             `json:"hall_id"`
+	HallName  string          `json:"hall_name"`
+	Rows      int             `json:"rows"`
+	Columns   int             `json:"columns"`
+	Aisles    []int           `json:"aisles"`
+	Grid      [][]SeatMapSeat `json:"grid"`
+}
+
+func BuildSeatMap(h Hall, s Session, held []SeatHold) SeatMap {
+	available := make(map[string]bool, len(s.AvailableSeats))
+	for _, code := range s.AvailableSeats {
+		available[code] = true
+	}
+	onHold := make(map[string]bool, len(held))
+	for _, hold := range held {
+		onHold[hold.Seat] = true
+	}
+
+	aisles := h.Aisles
+	if aisles == nil {
+		aisles = []int{}
+	}
+	m := SeatMap{
+		SessionID: s.ID,
+		HallID:    h.ID,
+		HallName:  h.Name,
+		Rows:      h.Rows,
+		Columns:   h.Columns,
+		Aisles:    aisles,
+		Grid:      make([][]SeatMapSeat, h.Rows),
+	}
+	for row := 0; row < h.Rows; row++ {
+		m.Grid[row] = make([]SeatMapSeat, h.Columns)
+		for col := 0; col < h.Columns; col++ {
+			code := seatCode(row, col)
+			status := SeatSold
+			switch {
+			case h.IsBlocked(code):
+				status = SeatBlocked
+			case available[code]:
+				status = SeatAvailable
+			case onHold[code]:
+				status = SeatHeld
+			}
+			m.Grid[row][col] = SeatMapSeat{
+				Code:     code,
+				Row:      rowLabel(row),
+				Number:   col + 1,
+				Category: h.Category(code),
+				Status:   status,
+			}
+		}
+	}
+	return m
+}
+
+func CreateSession(repos Repositories, s Session) (Session, error) {
+	if s.MovieID == 0 {
+		return Session{}, errors.New("movie_id is required")
+	}
+	if !s.StartTime.After(time.Now()) {
+		return Session{}, errors.New("start_time must be in the future")
+	}
+	if s.BasePrice <= 0 {
+		return Session{}, errors.New("base_price must be positive")
+	}
+	movie, ok, err := repos.Movies.GetByID(s.MovieID)
+	if err != nil {
+		return Session{}, err
+	}
+	if !ok {
+		return Session{}, ErrMovieNotFound
+	}
+	s.MovieTitle = movie.Title
+	if s.MinAge == 0 {
+		s.MinAge = movie.MinAge
+	}
+	s.EndTime = s.StartTime.Add(movieDuration(movie))
+	s.Status = SessionScheduled
+	if s.HallID != 0 {
+		h, ok, err := repos.Halls.GetByID(s.HallID)
+		if err != nil {
+			return Session{}, err
+		}
+		if !ok {
+			return Session{}, errors.New("hall not found")
+		}
+		if s.CinemaName != "" && s.CinemaName != h.CinemaName {
+			return Session{}, errors.New("hall does not belong to this cinema")
+		}
+		s.CinemaName = h.CinemaName
+		s.Hall = h.Name
+		s.AvailableSeats = h.SeatCodes()
+	}
+	c, ok, err := repos.Cinemas.GetByName(s.CinemaName)
+	if err != nil {
+		return Session{}, err
+	}
+	if !ok {
+		return Session{}, ErrCinemaNotFound
+	}
+	s.City = c.City
+	s.Timezone = c.Timezone
+	if s.HallID != 0 {
+		conflicts, err := hallConflicts(repos, s.HallID, []Session{s})
+		if err != nil {
+			return Session{}, err
+		}
+		if len(conflicts) > 0 {
+			return Session{}, fmt.Errorf("%w: %s", ErrScheduleConflict, conflicts[0].Reason)
+		}
+	}
+	return repos.Sessions.Add(s)
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type mongoHallRepository struct{}
+
+func NewMongoHallRepository() HallRepository {
+	return &mongoHallRepository{}
+}
+
+func (r *mongoHallRepository) Add(h Hall) (Hall, error) {
+	id, err := nextID("halls")
+	if err != nil {
+		return Hall{}, err
+	}
+	h.ID = id
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := service.HallsCollection().InsertOne(ctx, h); err != nil {
+		return Hall{}, err
+	}
+	return h, nil
+}
+
+func (r *mongoHallRepository) GetByID(id int) (Hall, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var h Hall
+	err := service.HallsCollection().FindOne(ctx, bson.M{"id": id}).Decode(&h)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Hall{}, false, nil
+		}
+		return Hall{}, false, err
+	}
+	return h, true, nil
+}
+
+func (r *mongoHallRepository) List(cinema string) ([]Hall, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if cinema != "" {
+		filter["cinema_name"] = cinema
+	}
+
+	cur, err := service.HallsCollection().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]Hall, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
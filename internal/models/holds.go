@@ -0,0 +1,238 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "held"
+	HoldSold     HoldStatus = "sold"
+	HoldReleased HoldStatus = "released"
+)
+
+type SeatHold struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SessionID int                `bson:"session_id" json:"session_id"`
+	Seat      string             `bson:"seat" json:"seat"`
+	Owner     string             `bson:"owner" json:"owner"`
+	OrderID   primitive.ObjectID `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	Status    HoldStatus         `bson:"status" json:"status"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+var (
+	ErrSeatHeld = errors.New("seat not available")
+	ErrSeatLost = errors.New("paid seat is no longer available")
+)
+
+func HoldSeats(repos Repositories, sessionID int, seats []string, owner string, ttl time.Duration) (Session, []SeatHold, error) {
+	if len(seats) == 0 {
+		return Session{}, nil, errors.New("at least one seat is required")
+	}
+	seen := make(map[string]bool, len(seats))
+	for _, seat := range seats {
+		if seat == "" || seen[seat] {
+			return Session{}, nil, fmt.Errorf("invalid seat list")
+		}
+		seen[seat] = true
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	holds := make([]SeatHold, 0, len(seats))
+	fresh := make([]string, 0, len(seats))
+	for _, seat := range seats {
+		existing, ok, err := repos.Holds.GetActive(sessionID, seat)
+		if err != nil {
+			return Session{}, nil, err
+		}
+		if !ok {
+			fresh = append(fresh, seat)
+			continue
+		}
+		if existing.Owner != owner || !existing.OrderID.IsZero() {
+			return Session{}, nil, ErrSeatHeld
+		}
+		holds = append(holds, existing)
+	}
+
+	var session Session
+	var err error
+	if len(fresh) > 0 {
+		session, err = repos.Sessions.ReserveSeats(sessionID, fresh)
+		if err != nil {
+			return Session{}, nil, err
+		}
+	} else {
+		session, _, err = repos.Sessions.GetByID(sessionID)
+		if err != nil {
+			return Session{}, nil, err
+		}
+	}
+
+	created := make([]SeatHold, 0, len(fresh))
+	rollback := func() {
+		for _, h := range created {
+			_, _ = repos.Holds.Transition(h.ID, HoldActive, HoldReleased)
+		}
+		for _, seat := range fresh {
+			_ = repos.Sessions.ReleaseSeat(sessionID, seat)
+		}
+	}
+	for _, seat := range fresh {
+		hold, err := repos.Holds.Create(SeatHold{
+			SessionID: sessionID,
+			Seat:      seat,
+			Owner:     owner,
+			Status:    HoldActive,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			rollback()
+			return Session{}, nil, err
+		}
+		created = append(created, hold)
+	}
+
+	for i := range holds {
+		holds[i].ExpiresAt = expiresAt
+		if err := repos.Holds.Extend(holds[i].ID, expiresAt); err != nil {
+			rollback()
+			return Session{}, nil, err
+		}
+	}
+	publishSeats(service.SeatHeld, sessionID, fresh...)
+	return session, append(holds, created...), nil
+}
+
+func ReleaseHold(repos Repositories, h SeatHold) error {
+	ok, err := repos.Holds.Transition(h.ID, HoldActive, HoldReleased)
+	if err != nil || !ok {
+		return err
+	}
+	if err := repos.Sessions.ReleaseSeat(h.SessionID, h.Seat); err != nil {
+		return err
+	}
+	publishSeats(service.SeatReleased, h.SessionID, h.Seat)
+	return nil
+}
+
+// Returns ErrSeatLost when a released hold could not be taken back; the other
+// seats are still sold so the caller can refund the whole order.
+func SellOrderHolds(repos Repositories, orderID primitive.ObjectID) error {
+	holds, err := repos.Holds.GetByOrder(orderID)
+	if err != nil {
+		return err
+	}
+	var lost []string
+	for _, h := range holds {
+		switch h.Status {
+		case HoldActive:
+			ok, err := repos.Holds.Transition(h.ID, HoldActive, HoldSold)
+			if err != nil {
+				return err
+			}
+			if ok {
+				publishSeats(service.SeatSold, h.SessionID, h.Seat)
+			}
+		case HoldReleased:
+			if _, err := repos.Sessions.ReserveSeats(h.SessionID, []string{h.Seat}); err != nil {
+				log.Printf("[HOLDS] paid order %s lost seat %s in session %d: %v", orderID.Hex(), h.Seat, h.SessionID, err)
+				lost = append(lost, h.Seat)
+				continue
+			}
+			if _, err := repos.Holds.Transition(h.ID, HoldReleased, HoldSold); err != nil {
+				return err
+			}
+			publishSeats(service.SeatSold, h.SessionID, h.Seat)
+		}
+	}
+	if len(lost) > 0 {
+		return fmt.Errorf("%w: %v", ErrSeatLost, lost)
+	}
+	return nil
+}
+
+func ReleaseOrderHolds(repos Repositories, orderID primitive.ObjectID) error {
+	holds, err := repos.Holds.GetByOrder(orderID)
+	if err != nil {
+		return err
+	}
+	for _, h := range holds {
+		if err := ReleaseHold(repos, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ReturnOrderSeats(repos Repositories, orderID primitive.ObjectID) error {
+	holds, err := repos.Holds.GetByOrder(orderID)
+	if err != nil {
+		return err
+	}
+	for _, h := range holds {
+		if h.Status == HoldReleased {
+			continue
+		}
+		ok, err := repos.Holds.Transition(h.ID, h.Status, HoldReleased)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := repos.Sessions.ReleaseSeat(h.SessionID, h.Seat); err != nil {
+				return err
+			}
+			publishSeats(service.SeatReleased, h.SessionID, h.Seat)
+		}
+	}
+	return nil
+}
+
+func ReleaseExpiredHolds(repos Repositories, now time.Time) (int, error) {
+	expired, err := repos.Holds.ListExpired(now)
+	if err != nil {
+		return 0, err
+	}
+	released := 0
+	for _, h := range expired {
+		if err := ReleaseHold(repos, h); err != nil {
+			return released, err
+		}
+		released++
+		if !h.OrderID.IsZero() {
+			if ok, _ := repos.Orders.UpdateStatus(h.OrderID, "reserved", "expired"); ok {
+				_ = ReleaseOrderPromo(repos, h.OrderID)
+				_ = ReverseOrderBonuses(repos, h.OrderID, "Reservation expired")
+			}
+		}
+	}
+	return released, nil
+}
+
+func StartHoldSweeper(repos Repositories, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := ReleaseExpiredHolds(repos, time.Now())
+			if err != nil {
+				log.Println("[HOLDS] sweep failed:", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[HOLDS] released %d expired seat holds", n)
+			}
+		}
+	}()
+}
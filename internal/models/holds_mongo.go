@@ -0,0 +1,232 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoHoldRepository struct{}
+
+func NewMongoHoldRepository() HoldRepository {
+	return &mongoHoldRepository{}
+}
+
+func (r *mongoHoldRepository) Create(h SeatHold) (SeatHold, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h.CreatedAt = time.Now()
+	h.UpdatedAt = h.CreatedAt
+	if h.Status == "" {
+		h.Status = HoldActive
+	}
+
+	res, err := service.HoldsCollection().InsertOne(ctx, h)
+	if err != nil {
+		return SeatHold{}, err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		h.ID = oid
+	}
+	return h, nil
+}
+
+func (r *mongoHoldRepository) GetActive(sessionID int, seat string) (SeatHold, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var h SeatHold
+	err := service.HoldsCollection().FindOne(ctx, bson.M{
+		"session_id": sessionID,
+		"seat":       seat,
+		"status":     HoldActive,
+	}).Decode(&h)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return SeatHold{}, false, nil
+		}
+		return SeatHold{}, false, err
+	}
+	return h, true, nil
+}
+
+func (r *mongoHoldRepository) GetByOrder(orderID primitive.ObjectID) ([]SeatHold, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := service.HoldsCollection().Find(ctx, bson.M{"order_id": orderID})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]SeatHold, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoHoldRepository) AttachOrder(id primitive.ObjectID, orderID primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.HoldsCollection().UpdateOne(ctx,
+		bson.M{
+			"_id":    id,
+			"status": HoldActive,
+			"$or":    []bson.M{{"order_id": bson.M{"$exists": false}}, {"order_id": orderID}},
+		},
+		bson.M{"$set": bson.M{"order_id": orderID, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (r *mongoHoldRepository) Extend(id primitive.ObjectID, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := service.HoldsCollection().UpdateOne(ctx,
+		bson.M{"_id": id, "status": HoldActive},
+		bson.M{"$set": bson.M{"expires_at": expiresAt, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+func (r *mongoHoldRepository) Transition(id primitive.ObjectID, from, to HoldStatus) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.HoldsCollection().UpdateOne(ctx,
+		bson.M{"_id": id, "status": from},
+		bson.M{"$set": bson.M{"status": to, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *mongoHoldRepository) ListExpired(now time.Time) ([]SeatHold, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := service.HoldsCollection().Find(ctx, bson.M{
+		"status":     HoldActive,
+		"expires_at": bson.M{"$lt": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]SeatHold, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoHoldRepository) ListActiveBySession(sessionID int) ([]SeatHold, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cur, err := service.HoldsCollection().Find(ctx, bson.M{
+		"session_id": sessionID,
+		"status":     HoldActive,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]SeatHold, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Change streams need a replica set. On a standalone server the stream is
+// skipped and each instance only sees events for holds it changed itself.
+func StartSeatHoldStream() {
+	go func() {
+		var resume bson.Raw
+		for {
+			err := watchSeatHolds(&resume)
+			holdStreamActive.Store(false)
+			var se mongo.ServerError
+			if errors.As(err, &se) && se.HasErrorCode(40573) {
+				log.Println("[HOLDS] change streams unavailable, seat events stay local to this instance:", err)
+				return
+			}
+			if errors.As(err, &se) && se.HasErrorCode(286) {
+				resume = nil
+			}
+			log.Println("[HOLDS] seat event stream stopped, reconnecting:", err)
+			time.Sleep(5 * time.Second)
+		}
+	}()
+}
+
+func watchSeatHolds(resume *bson.Raw) error {
+	ctx := context.Background()
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"$or": []bson.M{
+		{"operationType": "insert"},
+		{"updateDescription.updatedFields.status": bson.M{"$exists": true}},
+	}}}}}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if *resume != nil {
+		opts.SetResumeAfter(*resume)
+	}
+	stream, err := service.HoldsCollection().Watch(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(ctx)
+	holdStreamActive.Store(true)
+
+	for stream.Next(ctx) {
+		*resume = stream.ResumeToken()
+		var change struct {
+			OperationType     string   `bson:"operationType"`
+			FullDocument      SeatHold `bson:"fullDocument"`
+			UpdateDescription struct {
+				UpdatedFields struct {
+					Status HoldStatus `bson:"status"`
+				} `bson:"updatedFields"`
+			} `bson:"updateDescription"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			log.Println("[HOLDS] bad change event:", err)
+			continue
+		}
+		h := change.FullDocument
+		if h.SessionID == 0 {
+			continue
+		}
+		status := h.Status
+		if change.OperationType == "update" {
+			status = change.UpdateDescription.UpdatedFields.Status
+		}
+		service.SeatEvents().Publish(service.SeatEvent{
+			Type:      holdEventType(status),
+			SessionID: h.SessionID,
+			Seat:      h.Seat,
+			At:        h.UpdatedAt,
+		})
+	}
+	return stream.Err()
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errInjected = errors.New("injected failure")
+
+// flakyHolds fails Create after the given number of successful calls, or
+// every Extend when failExtend is set.
+type flakyHolds struct {
+	HoldRepository
+	createsLeft int
+	failExtend  bool
+}
+
+func (f *flakyHolds) Create(h SeatHold) (SeatHold, error) {
+	if f.createsLeft == 0 {
+		return SeatHold{}, errInjected
+	}
+	f.createsLeft--
+	return f.HoldRepository.Create(h)
+}
+
+func (f *flakyHolds) Extend(id primitive.ObjectID, expiresAt time.Time) error {
+	if f.failExtend {
+		return errInjected
+	}
+	return f.HoldRepository.Extend(id, expiresAt)
+}
+
+func newHoldTestSession(t *testing.T, repos Repositories) Session {
+	t.Helper()
+	s, err := repos.Sessions.Add(Session{
+		MovieTitle:     "Holds",
+		BasePrice:      2000,
+		AvailableSeats: []string{"A1", "A2", "A3", "A4"},
+		StartTime:      time.Now().Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("add session: %v", err)
+	}
+	return s
+}
+
+func assertAvailable(t *testing.T, repos Repositories, sessionID int, want ...string) {
+	t.Helper()
+	s, _, err := repos.Sessions.GetByID(sessionID)
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	got := slices.Clone(s.AvailableSeats)
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Fatalf("available seats = %v, want %v", got, want)
+	}
+}
+
+func assertActiveHolds(t *testing.T, repos Repositories, sessionID int, want int) {
+	t.Helper()
+	holds, err := repos.Holds.ListActiveBySession(sessionID)
+	if err != nil {
+		t.Fatalf("list holds: %v", err)
+	}
+	if len(holds) != want {
+		t.Fatalf("active holds = %d, want %d", len(holds), want)
+	}
+}
+
+func TestHoldSeatsHoldsEverySeat(t *testing.T) {
+	repos := NewMemoryRepositories()
+	s := newHoldTestSession(t, repos)
+
+	_, holds, err := HoldSeats(repos, s.ID, []string{"A1", "A2"}, "alice", time.Minute)
+	if err != nil {
+		t.Fatalf("HoldSeats: %v", err)
+	}
+	if len(holds) != 2 {
+		t.Fatalf("holds = %d, want 2", len(holds))
+	}
+	assertAvailable(t, repos, s.ID, "A3", "A4")
+	assertActiveHolds(t, repos, s.ID, 2)
+}
+
+func TestHoldSeatsSoldSeatLeavesOthersFree(t *testing.T) {
+	repos := NewMemoryRepositories()
+	s := newHoldTestSession(t, repos)
+	if _, err := repos.Sessions.ReserveSeats(s.ID, []string{"A2"}); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+
+	if _, _, err := HoldSeats(repos, s.ID, []string{"A1", "A2", "A3"}, "alice", time.Minute); err == nil {
+		t.Fatal("HoldSeats succeeded with a sold seat in the request")
+	}
+	assertAvailable(t, repos, s.ID, "A1", "A3", "A4")
+	assertActiveHolds(t, repos, s.ID, 0)
+}
+
+func TestHoldSeatsRejectsAnotherOwnersHold(t *testing.T) {
+	repos := NewMemoryRepositories()
+	s := newHoldTestSession(t, repos)
+	if _, _, err := HoldSeats(repos, s.ID, []string{"A2"}, "alice", time.Minute); err != nil {
+		t.Fatalf("first hold: %v", err)
+	}
+
+	_, _, err := HoldSeats(repos, s.ID, []string{"A1", "A2"}, "bob", time.Minute)
+	if !errors.Is(err, ErrSeatHeld) {
+		t.Fatalf("err = %v, want ErrSeatHeld", err)
+	}
+	assertAvailable(t, repos, s.ID, "A1", "A3", "A4")
+	assertActiveHolds(t, repos, s.ID, 1)
+}
+
+func TestHoldSeatsRollsBackWhenCreateFails(t *testing.T) {
+	repos := NewMemoryRepositories()
+	s := newHoldTestSession(t, repos)
+	repos.Holds = &flakyHolds{HoldRepository: repos.Holds, createsLeft: 1}
+
+	_, _, err := HoldSeats(repos, s.ID, []string{"A1", "A2", "A3"}, "alice", time.Minute)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("err = %v, want injected failure", err)
+	}
+	assertAvailable(t, repos, s.ID, "A1", "A2", "A3", "A4")
+	assertActiveHolds(t, repos, s.ID, 0)
+}
+
+func TestHoldSeatsRollsBackWhenExtendFails(t *testing.T) {
+	repos := NewMemoryRepositories()
+	s := newHoldTestSession(t, repos)
+	if _, _, err := HoldSeats(repos, s.ID, []string{"A1"}, "alice", time.Minute); err != nil {
+		t.Fatalf("first hold: %v", err)
+	}
+	repos.Holds = &flakyHolds{HoldRepository: repos.Holds, createsLeft: -1, failExtend: true}
+
+	_, _, err := HoldSeats(repos, s.ID, []string{"A1", "A2"}, "alice", time.Minute)
+	if !errors.Is(err, errInjected) {
+		t.Fatalf("err = %v, want injected failure", err)
+	}
+	// A1 stays with its original hold; only the newly taken A2 is given back.
+	assertAvailable(t, repos, s.ID, "A2", "A3", "A4")
+	assertActiveHolds(t, repos, s.ID, 1)
+}
+
+func TestHoldSeatsConcurrentOverlapHasOneWinner(t *testing.T) {
+	repos := NewMemoryRepositories()
+	s := newHoldTestSession(t, repos)
+
+	var wins atomic.Int32
+	var wg sync.WaitGroup
+	for _, owner := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := HoldSeats(repos, s.ID, []string{"A2", "A3"}, owner, time.Minute); err == nil {
+				wins.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := wins.Load(); n != 1 {
+		t.Fatalf("winners = %d, want 1", n)
+	}
+	assertAvailable(t, repos, s.ID, "A1", "A4")
+	assertActiveHolds(t, repos, s.ID, 2)
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the unique indexes the Mongo repositories rely on to
+// reject duplicates that slip past their read-then-insert checks. Creating an
+// index that already exists is a no-op. Each index is attempted even if another
+// fails, and every failure names its collection.
+func EnsureIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	unique := []struct {
+		coll  *mongo.Collection
+		model mongo.IndexModel
+	}{
+		{service.UsersCollection(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true).SetCollation(emailCollation),
+		}},
+		{service.PromoCodesCollection(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}},
+		{service.CinemasCollection(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}},
+		{service.PaymentsCollection(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "invoice_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		}},
+		// One earn entry per order, so bonus credits and the backfill cannot
+		// double up when they race.
+		{service.LedgerCollection(), mongo.IndexModel{
+			Keys: bson.D{{Key: "order_id", Value: 1}, {Key: "type", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"type": LedgerEarn}),
+		}},
+	}
+	var errs []error
+	for _, u := range unique {
+		field := u.model.Keys.(bson.D)[0].Key
+		if _, err := u.coll.Indexes().CreateOne(ctx, u.model); err != nil {
+			if u.coll.Name() == service.UsersCollection().Name() {
+				err = describeEmailConflicts(ctx, err)
+			}
+			errs = append(errs, fmt.Errorf("unique index %s.%s: %w", u.coll.Name(), field, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// describeEmailConflicts lists accounts whose emails differ only in case; they
+// must be merged or renamed by hand before the case-insensitive index can exist.
+func describeEmailConflicts(ctx context.Context, cause error) error {
+	cur, err := service.UsersCollection().Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$toLower": "$email"},
+			"emails": bson.M{"$push": "$email"},
+			"count":  bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$limit", Value: 20}},
+	})
+	if err != nil {
+		return cause
+	}
+	defer cur.Close(ctx)
+
+	var groups []struct {
+		Emails []string `bson:"emails"`
+	}
+	if err := cur.All(ctx, &groups); err != nil || len(groups) == 0 {
+		return cause
+	}
+	conflicts := make([]string, 0, len(groups))
+	for _, g := range groups {
+		conflicts = append(conflicts, strings.Join(g.Emails, " / "))
+	}
+	return fmt.Errorf("%w; accounts differing only in email case must be merged first: %s", cause, strings.Join(conflicts, "; "))
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"cinema/internal/service"
+)
+
+type LoginAttempt struct {
+	Key         string    `bson:"_id" json:"key"`
+	Failures    int       `bson:"failures" json:"failures"`
+	LastFailure time.Time `bson:"last_failure" json:"last_failure"`
+	LockedUntil time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+}
+
+func NewLoginAttemptRepository() LoginAttemptRepository {
+	if service.LoginAttemptStore() == "mongo" {
+		return NewMongoLoginAttemptRepository()
+	}
+	return NewMemoryLoginAttemptRepository()
+}
+
+func accountAttemptKey(email string) string { return "email:" + email }
+func ipAttemptKey(ip string) string         { return "ip:" + ip }
+
+func LoginLockedUntil(repos Repositories, email, ip string) (time.Time, error) {
+	var until time.Time
+	for _, key := range []string{accountAttemptKey(email), ipAttemptKey(ip)} {
+		a, ok, err := repos.LoginAttempts.Get(key)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ok && a.LockedUntil.After(time.Now()) && a.LockedUntil.After(until) {
+			until = a.LockedUntil
+		}
+	}
+	return until, nil
+}
+
+func RecordLoginFailure(repos Repositories, email, ip string, notify bool) error {
+	now := time.Now()
+	window := service.LoginAttemptWindow()
+
+	acct, err := repos.LoginAttempts.RecordFailure(accountAttemptKey(email), now, window)
+	if err != nil {
+		return err
+	}
+	if d := service.LockoutDuration(acct.Failures, service.LoginMaxAttempts()); d > 0 {
+		until := now.Add(d)
+		if err := repos.LoginAttempts.Lock(acct.Key, until); err != nil {
+			return err
+		}
+		if notify && acct.Failures == service.LoginMaxAttempts() {
+			service.SendLockoutNotification(email, until)
+		}
+	}
+
+	byIP, err := repos.LoginAttempts.RecordFailure(ipAttemptKey(ip), now, window)
+	if err != nil {
+		return err
+	}
+	if d := service.LockoutDuration(byIP.Failures, service.LoginMaxAttemptsPerIP()); d > 0 {
+		return repos.LoginAttempts.Lock(byIP.Key, now.Add(d))
+	}
+	return nil
+}
+
+func RecordLoginSuccess(repos Repositories, email string) error {
+	return repos.LoginAttempts.Reset(accountAttemptKey(email))
+}
+
+func UnlockAccount(repos Repositories, email string) error {
+	return repos.LoginAttempts.Reset(accountAttemptKey(email))
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoLoginAttemptRepository struct{}
+
+func NewMongoLoginAttemptRepository() LoginAttemptRepository {
+	return &mongoLoginAttemptRepository{}
+}
+
+func (r *mongoLoginAttemptRepository) Get(key string) (LoginAttempt, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var a LoginAttempt
+	err := service.LoginAttemptsCollection().FindOne(ctx, bson.M{"_id": key}).Decode(&a)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return LoginAttempt{}, false, nil
+		}
+		return LoginAttempt{}, false, err
+	}
+	return a, true, nil
+}
+
+func (r *mongoLoginAttemptRepository) RecordFailure(key string, now time.Time, window time.Duration) (LoginAttempt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "failures", Value: bson.M{"$cond": bson.A{
+			bson.M{"$gte": bson.A{"$last_failure", now.Add(-window)}},
+			bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+			1,
+		}}},
+		{Key: "last_failure", Value: now},
+	}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var a LoginAttempt
+	err := service.LoginAttemptsCollection().FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&a)
+	return a, err
+}
+
+func (r *mongoLoginAttemptRepository) Lock(key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := service.LoginAttemptsCollection().UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{"$set": bson.M{"locked_until": until}},
+	)
+	return err
+}
+
+func (r *mongoLoginAttemptRepository) Reset(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := service.LoginAttemptsCollection().DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
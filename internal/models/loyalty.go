@@ -0,0 +1,226 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LedgerEntryType string
+
+const (
+	LedgerEarn     LedgerEntryType = "earn"
+	LedgerSpend    LedgerEntryType = "spend"
+	LedgerExpire   LedgerEntryType = "expire"
+	LedgerReversal LedgerEntryType = "reversal"
+)
+
+type LedgerEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email     string             `bson:"email" json:"email"`
+	Type      LedgerEntryType    `bson:"type" json:"type"`
+	Amount    int                `bson:"amount" json:"amount"`
+	OrderID   primitive.ObjectID `bson:"order_id,omitempty" json:"order_id,omitempty"`
+	Reverses  primitive.ObjectID `bson:"reverses,omitempty" json:"reverses,omitempty"`
+	ExpiresAt time.Time          `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+var (
+	ErrInsufficientBonuses = errors.New("not enough bonuses")
+	ErrAlreadyCredited     = errors.New("bonuses already credited for this order")
+)
+
+func orderAccount(o Order) string {
+	if o.UserEmail != "" {
+		return o.UserEmail
+	}
+	return o.CustomerEmail
+}
+
+// clampDeduction returns how much of amount can be applied to balance without
+// taking it below zero, or below where it already is if it is negative.
+func clampDeduction(balance, amount int) int {
+	floor := min(balance, 0)
+	if balance+amount < floor {
+		return floor - balance
+	}
+	return amount
+}
+
+func CreditOrderBonuses(repos Repositories, o Order) error {
+	if o.BonusesEarned <= 0 {
+		return nil
+	}
+	entries, err := repos.Ledger.ListByOrder(o.ID)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Type == LedgerEarn {
+			return nil
+		}
+	}
+	_, err = repos.Ledger.Append(LedgerEntry{
+		Email:     orderAccount(o),
+		Type:      LedgerEarn,
+		Amount:    o.BonusesEarned,
+		OrderID:   o.ID,
+		ExpiresAt: time.Now().Add(service.BonusTTL()),
+		Note:      "Order " + o.ID.Hex() + " paid",
+	})
+	if errors.Is(err, ErrAlreadyCredited) {
+		return nil
+	}
+	return err
+}
+
+// BackfillOrderBonuses credits paid orders placed before the ledger existed,
+// whose bonuses only live on Order.BonusesEarned. Orders that already have an
+// earn entry are skipped, so it is safe to run on every start.
+func BackfillOrderBonuses(repos Repositories) (int, error) {
+	orders, err := repos.Orders.GetAll()
+	if err != nil {
+		return 0, err
+	}
+	credited := 0
+	for _, o := range orders {
+		if o.PaymentStatus != "paid" || o.BonusesEarned <= 0 {
+			continue
+		}
+		entries, err := repos.Ledger.ListByOrder(o.ID)
+		if err != nil {
+			return credited, err
+		}
+		if slices.ContainsFunc(entries, func(e LedgerEntry) bool { return e.Type == LedgerEarn }) {
+			continue
+		}
+		if err := CreditOrderBonuses(repos, o); err != nil {
+			return credited, err
+		}
+		credited++
+	}
+	return credited, nil
+}
+
+func ReverseOrderBonuses(repos Repositories, orderID primitive.ObjectID, note string) error {
+	entries, err := repos.Ledger.ListByOrder(orderID)
+	if err != nil {
+		return err
+	}
+	reversed := make(map[primitive.ObjectID]bool)
+	for _, e := range entries {
+		if e.Type == LedgerReversal {
+			reversed[e.Reverses] = true
+		}
+	}
+	for _, e := range entries {
+		if reversed[e.ID] || (e.Type != LedgerEarn && e.Type != LedgerSpend) {
+			continue
+		}
+		_, err := repos.Ledger.Append(LedgerEntry{
+			Email:    e.Email,
+			Type:     LedgerReversal,
+			Amount:   -e.Amount,
+			OrderID:  orderID,
+			Reverses: e.ID,
+			Note:     note,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expiringAmount(entries []LedgerEntry, now time.Time) int {
+	reversed := make(map[primitive.ObjectID]LedgerEntryType)
+	types := make(map[primitive.ObjectID]LedgerEntryType, len(entries))
+	for _, e := range entries {
+		types[e.ID] = e.Type
+	}
+	for _, e := range entries {
+		if e.Type == LedgerReversal {
+			reversed[e.Reverses] = types[e.Reverses]
+		}
+	}
+
+	expiredEarned, used, balance := 0, 0, 0
+	for _, e := range entries {
+		balance += e.Amount
+		switch e.Type {
+		case LedgerEarn:
+			if _, ok := reversed[e.ID]; !ok && !e.ExpiresAt.IsZero() && !e.ExpiresAt.After(now) {
+				expiredEarned += e.Amount
+			}
+		case LedgerSpend, LedgerExpire:
+			if _, ok := reversed[e.ID]; !ok {
+				used -= e.Amount
+			}
+		}
+	}
+
+	n := expiredEarned - used
+	if n > balance {
+		n = balance
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+func ExpireBonuses(repos Repositories, now time.Time) (int, error) {
+	emails, err := repos.Ledger.AccountsWithExpiredEarnings(now)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, email := range emails {
+		entries, err := repos.Ledger.History(email)
+		if err != nil {
+			return total, err
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+		})
+		n := expiringAmount(entries, now)
+		if n == 0 {
+			continue
+		}
+		if _, err := repos.Ledger.Append(LedgerEntry{
+			Email:  email,
+			Type:   LedgerExpire,
+			Amount: -n,
+			Note:   "Bonuses expired",
+		}); err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func StartBonusExpirySweeper(repos Repositories, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := ExpireBonuses(repos, time.Now())
+			if err != nil {
+				log.Println("[BONUS] expiry sweep failed:", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[BONUS] expired %d bonuses", n)
+			}
+		}
+	}()
+}
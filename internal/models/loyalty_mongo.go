@@ -0,0 +1,223 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type balanceDoc struct {
+	Email   string `bson:"_id"`
+	Balance int    `bson:"balance"`
+}
+
+type mongoLedgerRepository struct{}
+
+func NewMongoLedgerRepository() LedgerRepository {
+	return &mongoLedgerRepository{}
+}
+
+func (r *mongoLedgerRepository) insert(ctx context.Context, e LedgerEntry) (LedgerEntry, error) {
+	e.CreatedAt = time.Now()
+	res, err := service.LedgerCollection().InsertOne(ctx, e)
+	if err != nil {
+		return LedgerEntry{}, err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		e.ID = oid
+	}
+	return e, nil
+}
+
+// Set once the server has refused a transaction, i.e. it is a standalone
+// node; ledger writes then fall back to compensating on failure.
+var ledgerTxnUnsupported atomic.Bool
+
+// runLedgerTxn runs fn so that a balance change and its ledger entry are
+// committed together. Standalone servers have no transactions, so there fn
+// runs on its own and record undoes the balance change if the entry fails.
+func runLedgerTxn(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !ledgerTxnUnsupported.Load() {
+		err := service.MongoClient.UseSession(ctx, func(sc mongo.SessionContext) error {
+			_, err := sc.WithTransaction(sc, func(tc mongo.SessionContext) (any, error) {
+				return nil, fn(tc)
+			})
+			return err
+		})
+		var se mongo.ServerError
+		if !errors.As(err, &se) || !se.HasErrorCode(20) {
+			return err
+		}
+		ledgerTxnUnsupported.Store(true)
+		log.Println("[BONUS] MongoDB transactions unavailable, ledger writes are not atomic:", err)
+	}
+	return fn(ctx)
+}
+
+// record inserts the entry for a balance change that was already applied.
+func (r *mongoLedgerRepository) record(ctx context.Context, e LedgerEntry) (LedgerEntry, error) {
+	out, err := r.insert(ctx, e)
+	if mongo.IsDuplicateKeyError(err) {
+		err = ErrAlreadyCredited
+	}
+	if err != nil && e.Amount != 0 && mongo.SessionFromContext(ctx) == nil {
+		if _, uerr := service.BalancesCollection().UpdateOne(ctx,
+			bson.M{"_id": e.Email},
+			bson.M{"$inc": bson.M{"balance": -e.Amount}},
+		); uerr != nil {
+			log.Printf("[BONUS] balance of %s is off by %d, needs manual review: %v", e.Email, e.Amount, uerr)
+		}
+	}
+	return out, err
+}
+
+// adjust applies amount to the cached balance and returns how much was
+// applied. Deductions stop at zero so a reversal or expiry can never push an
+// account negative.
+func (r *mongoLedgerRepository) adjust(ctx context.Context, email string, amount int) (int, error) {
+	if amount >= 0 {
+		_, err := service.BalancesCollection().UpdateOne(ctx,
+			bson.M{"_id": email},
+			bson.M{"$inc": bson.M{"balance": amount}},
+			options.Update().SetUpsert(true),
+		)
+		return amount, err
+	}
+
+	balance := bson.M{"$ifNull": bson.A{"$balance", 0}}
+	floor := bson.M{"$min": bson.A{balance, 0}}
+	var before balanceDoc
+	err := service.BalancesCollection().FindOneAndUpdate(ctx,
+		bson.M{"_id": email},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"balance": bson.M{"$max": bson.A{bson.M{"$add": bson.A{balance, amount}}, floor}},
+		}}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&before)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, err
+	}
+	return clampDeduction(before.Balance, amount), nil
+}
+
+func (r *mongoLedgerRepository) Append(e LedgerEntry) (LedgerEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var out LedgerEntry
+	err := runLedgerTxn(ctx, func(ctx context.Context) error {
+		entry := e
+		applied, err := r.adjust(ctx, entry.Email, entry.Amount)
+		if err != nil {
+			return err
+		}
+		entry.Amount = applied
+		out, err = r.record(ctx, entry)
+		return err
+	})
+	if err != nil {
+		return LedgerEntry{}, err
+	}
+	return out, nil
+}
+
+func (r *mongoLedgerRepository) Spend(email string, amount int, orderID primitive.ObjectID) (LedgerEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var out LedgerEntry
+	err := runLedgerTxn(ctx, func(ctx context.Context) error {
+		res, err := service.BalancesCollection().UpdateOne(ctx,
+			bson.M{"_id": email, "balance": bson.M{"$gte": amount}},
+			bson.M{"$inc": bson.M{"balance": -amount}},
+		)
+		if err != nil {
+			return err
+		}
+		if res.ModifiedCount == 0 {
+			return ErrInsufficientBonuses
+		}
+		out, err = r.record(ctx, LedgerEntry{
+			Email:   email,
+			Type:    LedgerSpend,
+			Amount:  -amount,
+			OrderID: orderID,
+			Note:    "Order " + orderID.Hex() + " checkout",
+		})
+		return err
+	})
+	if err != nil {
+		return LedgerEntry{}, err
+	}
+	return out, nil
+}
+
+func (r *mongoLedgerRepository) Balance(email string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var b balanceDoc
+	err := service.BalancesCollection().FindOne(ctx, bson.M{"_id": email}).Decode(&b)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return b.Balance, nil
+}
+
+func (r *mongoLedgerRepository) find(filter bson.M) ([]LedgerEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := service.LedgerCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]LedgerEntry, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoLedgerRepository) History(email string) ([]LedgerEntry, error) {
+	return r.find(bson.M{"email": email})
+}
+
+func (r *mongoLedgerRepository) ListByOrder(orderID primitive.ObjectID) ([]LedgerEntry, error) {
+	return r.find(bson.M{"order_id": orderID})
+}
+
+func (r *mongoLedgerRepository) AccountsWithExpiredEarnings(now time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	values, err := service.LedgerCollection().Distinct(ctx, "email", bson.M{
+		"type":       LedgerEarn,
+		"expires_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestReverseOrderBonusesNeverGoesNegative(t *testing.T) {
+	repos := NewMemoryRepositories()
+	const email = "member@example.com"
+	earnedOn := primitive.NewObjectID()
+
+	if _, err := repos.Ledger.Append(LedgerEntry{
+		Email:     email,
+		Type:      LedgerEarn,
+		Amount:    100,
+		OrderID:   earnedOn,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("earn: %v", err)
+	}
+	if _, err := repos.Ledger.Spend(email, 80, primitive.NewObjectID()); err != nil {
+		t.Fatalf("spend: %v", err)
+	}
+
+	if err := ReverseOrderBonuses(repos, earnedOn, "Refunded"); err != nil {
+		t.Fatalf("reverse: %v", err)
+	}
+	if balance, _ := repos.Ledger.Balance(email); balance != 0 {
+		t.Fatalf("balance = %d, want 0", balance)
+	}
+	if n := countLedger(t, repos, earnedOn, LedgerReversal); n != 1 {
+		t.Fatalf("reversal entries = %d, want 1", n)
+	}
+
+	// A second reversal run finds the existing entry and does nothing.
+	if err := ReverseOrderBonuses(repos, earnedOn, "Refunded"); err != nil {
+		t.Fatalf("second reverse: %v", err)
+	}
+	if n := countLedger(t, repos, earnedOn, LedgerReversal); n != 1 {
+		t.Fatalf("reversal entries after retry = %d, want 1", n)
+	}
+}
+
+func TestClampDeduction(t *testing.T) {
+	tests := []struct{ balance, amount, want int }{
+		{100, -30, -30},
+		{20, -30, -20},
+		{0, -30, 0},
+		{-10, -30, 0},
+	}
+	for _, tt := range tests {
+		if got := clampDeduction(tt.balance, tt.amount); got != tt.want {
+			t.Errorf("clampDeduction(%d, %d) = %d, want %d", tt.balance, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestBackfillOrderBonusesCreditsPaidOrdersOnce(t *testing.T) {
+	repos := NewMemoryRepositories()
+	const email = "legacy@example.com"
+	save := func(status string, bonuses int) Order {
+		t.Helper()
+		o, err := repos.Orders.Save(Order{
+			ID:            primitive.NewObjectID(),
+			CustomerEmail: email,
+			PaymentStatus: status,
+			BonusesEarned: bonuses,
+		})
+		if err != nil {
+			t.Fatalf("save order: %v", err)
+		}
+		return o
+	}
+	save("paid", 50)
+	save("paid", 20)
+	save("refunded", 30)
+	save("reserved", 40)
+	credited := save("paid", 10)
+	if err := CreditOrderBonuses(repos, credited); err != nil {
+		t.Fatalf("credit: %v", err)
+	}
+
+	n, err := BackfillOrderBonuses(repos)
+	if err != nil || n != 2 {
+		t.Fatalf("first backfill: n=%d err=%v, want 2", n, err)
+	}
+	n, err = BackfillOrderBonuses(repos)
+	if err != nil || n != 0 {
+		t.Fatalf("second backfill: n=%d err=%v, want 0", n, err)
+	}
+	if balance, _ := repos.Ledger.Balance(email); balance != 80 {
+		t.Fatalf("balance = %d, want 80", balance)
+	}
+}
+
+func TestLedgerRejectsSecondEarnForOrder(t *testing.T) {
+	repos := NewMemoryRepositories()
+	order := primitive.NewObjectID()
+	earn := LedgerEntry{Email: "a@example.com", Type: LedgerEarn, Amount: 10, OrderID: order}
+	if _, err := repos.Ledger.Append(earn); err != nil {
+		t.Fatalf("first earn: %v", err)
+	}
+	if _, err := repos.Ledger.Append(earn); !errors.Is(err, ErrAlreadyCredited) {
+		t.Fatalf("second earn: err = %v, want ErrAlreadyCredited", err)
+	}
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"cinema/internal/service"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -13,7 +15,34 @@ type Movie struct {
 	PosterPath  string  `json:"poster_path" bson:"poster_path"`
 	ReleaseDate string  `json:"release_date" bson:"release_date"`
 	VoteAverage float64 `json:"vote_average" bson:"vote_average"`
+	Popularity  float64 `json:"popularity,omitempty" bson:"popularity,omitempty"`
 	Adult       bool    `json:"adult" bson:"adult"`
+
+	OriginalTitle string            `json:"original_title,omitempty" bson:"original_title,omitempty"`
+	BackdropPath  string            `json:"backdrop_path,omitempty" bson:"backdrop_path,omitempty"`
+	Runtime       int               `json:"runtime,omitempty" bson:"runtime,omitempty"`
+	Genres        []string          `json:"genres,omitempty" bson:"genres,omitempty"`
+	AgeRating     string            `json:"age_rating,omitempty" bson:"age_rating,omitempty"`
+	MinAge        int               `json:"min_age,omitempty" bson:"min_age,omitempty"`
+	Trailers      []Trailer         `json:"trailers,omitempty" bson:"trailers,omitempty"`
+	Cast          []CastMember      `json:"cast,omitempty" bson:"cast,omitempty"`
+	Posters       map[string]string `json:"posters,omitempty" bson:"posters,omitempty"`
+
+	ImportedAt time.Time `json:"imported_at,omitempty" bson:"imported_at,omitempty"`
+	SyncedAt   time.Time `json:"synced_at,omitempty" bson:"synced_at,omitempty"`
+}
+
+type Trailer struct {
+	Name string `json:"name" bson:"name"`
+	Site string `json:"site" bson:"site"`
+	Key  string `json:"key" bson:"key"`
+	URL  string `json:"url" bson:"url"`
+}
+
+type CastMember struct {
+	Name        string `json:"name" bson:"name"`
+	Character   string `json:"character" bson:"character"`
+	ProfilePath string `json:"profile_path,omitempty" bson:"profile_path,omitempty"`
 }
 
 type Session struct {
@@ -26,9 +55,27 @@ type Session struct {
 
 	TotalSeats int `json:"total_seats" bson:"total_seats"`
 
+	Format string `json:"format,omitempty" bson:"format,omitempty"`
+	MinAge int    `json:"min_age,omitempty" bson:"min_age,omitempty"`
+
 	CinemaName string    `json:"cinema_name" bson:"cinema_name"`
+	City       string    `json:"city,omitempty" bson:"city,omitempty"`
+	Timezone   string    `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	HallID     int       `json:"hall_id,omitempty" bson:"hall_id,omitempty"`
 	Hall       string    `json:"hall,omitempty" bson:"hall,omitempty"`
 	StartTime  time.Time `json:"start_time" bson:"start_time"`
+	EndTime    time.Time `json:"end_time,omitempty" bson:"end_time,omitempty"`
+
+	Status       SessionStatus `json:"status,omitempty" bson:"status,omitempty"`
+	CancelledAt  time.Time     `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+	CancelReason string        `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty"`
+}
+
+type OrderItem struct {
+	Seat      string              `bson:"seat" json:"seat"`
+	Category  string              `bson:"category,omitempty" json:"category,omitempty"`
+	Price     float64             `bson:"price" json:"price"`
+	Breakdown []service.PriceLine `bson:"breakdown,omitempty" json:"breakdown,omitempty"`
 }
 
 type Order struct {
@@ -37,13 +84,33 @@ type Order struct {
 	MovieTitle    string             `json:"movie_title" bson:"movie_title"`
 	FinalPrice    float64            `json:"final_price" bson:"final_price"`
 	PromoCode     string             `json:"promo_code" bson:"promo_code"`
+	Subtotal      float64            `json:"subtotal,omitempty" bson:"subtotal,omitempty"`
+	AppliedPromo  string             `json:"applied_promo,omitempty" bson:"applied_promo,omitempty"`
+	PromoDiscount float64            `json:"promo_discount,omitempty" bson:"promo_discount,omitempty"`
 	BonusesEarned int                `json:"bonuses_earned" bson:"bonuses_earned"`
+	BonusesSpent  int                `json:"bonuses_spent,omitempty" bson:"bonuses_spent,omitempty"`
+	UserEmail     string             `json:"user_email,omitempty" bson:"user_email,omitempty"`
 
 	SessionID  int       `bson:"session_id" json:"session_id"`
 	CinemaName string    `bson:"cinema_name" json:"cinema_name"`
 	Hall       string    `bson:"hall" json:"hall"`
 	StartTime  time.Time `bson:"start_time" json:"start_time"`
+	Timezone   string    `bson:"timezone,omitempty" json:"timezone,omitempty"`
 	Seat       string    `bson:"seat" json:"seat"`
 
-	PaymentStatus string `bson:"payment_status" json:"payment_status"`
+	Items []OrderItem `bson:"items,omitempty" json:"items,omitempty"`
+
+	PaymentStatus string    `bson:"payment_status" json:"payment_status"`
+	HoldExpiresAt time.Time `bson:"hold_expires_at,omitempty" json:"hold_expires_at,omitempty"`
+
+	CheckedInAt time.Time `bson:"checked_in_at,omitempty" json:"checked_in_at,omitempty"`
+	CheckedInBy string    `bson:"checked_in_by,omitempty" json:"checked_in_by,omitempty"`
+
+	SessionChangedAt time.Time `bson:"session_changed_at,omitempty" json:"session_changed_at,omitempty"`
+}
+
+// OwnedBy reports whether the signed-in account email placed the order.
+// CustomerEmail is typed in at checkout and only used for contact.
+func (o Order) OwnedBy(email string) bool {
+	return email != "" && o.UserEmail == email
 }
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cinema/internal/service"
+)
+
+const (
+	defaultMovieSearchPerPage = 20
+	maxMovieSearchPerPage     = 50
+	movieSearchTMDBPages      = 2
+)
+
+type MovieSearchQuery struct {
+	Query      string
+	Year       int
+	Genre      string
+	NowShowing bool
+	Page       int
+	PerPage    int
+}
+
+type MovieSearchResult struct {
+	Movie
+	InCatalog        bool       `json:"in_catalog"`
+	UpcomingSessions int        `json:"upcoming_sessions"`
+	NextSession      *time.Time `json:"next_session,omitempty"`
+	Score            float64    `json:"score"`
+}
+
+type MovieSearchPage struct {
+	Query        string              `json:"query"`
+	Page         int                 `json:"page"`
+	PerPage      int                 `json:"per_page"`
+	TotalResults int                 `json:"total_results"`
+	TotalPages   int                 `json:"total_pages"`
+	Partial      bool                `json:"partial,omitempty"`
+	Results      []MovieSearchResult `json:"results"`
+}
+
+func SearchMovies(repos Repositories, q MovieSearchQuery) (MovieSearchPage, error) {
+	q.Query = strings.TrimSpace(q.Query)
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PerPage < 1 {
+		q.PerPage = defaultMovieSearchPerPage
+	}
+	if q.PerPage > maxMovieSearchPerPage {
+		q.PerPage = maxMovieSearchPerPage
+	}
+	page := MovieSearchPage{Query: q.Query, Page: q.Page, PerPage: q.PerPage, Results: []MovieSearchResult{}}
+
+	upcoming, err := upcomingSessionsByMovie(repos)
+	if err != nil {
+		return page, err
+	}
+	catalog, err := repos.Movies.List()
+	if err != nil {
+		return page, err
+	}
+
+	byID := map[int]*MovieSearchResult{}
+	var candidates []*MovieSearchResult
+	add := func(m Movie, inCatalog bool) {
+		if _, seen := byID[m.ID]; seen {
+			return
+		}
+		r := &MovieSearchResult{Movie: m, InCatalog: inCatalog}
+		byID[m.ID] = r
+		candidates = append(candidates, r)
+	}
+
+	for _, m := range catalog {
+		if q.Query == "" || titleRelevance(q.Query, m) > 0 {
+			add(m, true)
+		}
+	}
+	if q.Query != "" && !q.NowShowing && service.TMDBEnabled() {
+		for p := 1; p <= movieSearchTMDBPages; p++ {
+			res, err := service.DefaultTMDBClient().SearchMovies(q.Query, p)
+			if err != nil {
+				log.Printf("[MOVIES] tmdb search %q failed: %v", q.Query, err)
+				page.Partial = true
+				break
+			}
+			for _, t := range res.Results {
+				add(MovieFromTMDB(t), false)
+			}
+			if p >= res.TotalPages {
+				break
+			}
+		}
+	}
+
+	var matched []MovieSearchResult
+	for _, r := range candidates {
+		if s, ok := upcoming[r.ID]; ok {
+			r.UpcomingSessions = s.count
+			next := s.next
+			r.NextSession = &next
+		}
+		if !r.matches(q) {
+			continue
+		}
+		r.Score = r.score(q.Query)
+		matched = append(matched, *r)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Score != matched[j].Score {
+			return matched[i].Score > matched[j].Score
+		}
+		return matched[i].Title < matched[j].Title
+	})
+
+	page.TotalResults = len(matched)
+	page.TotalPages = (len(matched) + q.PerPage - 1) / q.PerPage
+	if start := (q.Page - 1) * q.PerPage; start < len(matched) {
+		page.Results = matched[start:min(start+q.PerPage, len(matched))]
+	}
+	return page, nil
+}
+
+func (r MovieSearchResult) matches(q MovieSearchQuery) bool {
+	if q.NowShowing && r.UpcomingSessions == 0 {
+		return false
+	}
+	if q.Year > 0 && !strings.HasPrefix(r.ReleaseDate, strconv.Itoa(q.Year)) {
+		return false
+	}
+	if q.Genre != "" {
+		found := false
+		for _, g := range r.Genres {
+			if strings.EqualFold(g, q.Genre) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r MovieSearchResult) score(query string) float64 {
+	score := float64(titleRelevance(query, r.Movie))
+	if query != "" && score == 0 {
+		score = 10
+	}
+	if r.UpcomingSessions > 0 {
+		score += 25
+	}
+	if r.InCatalog {
+		score += 10
+	}
+	score += r.VoteAverage / 2
+	score += math.Min(math.Log1p(r.Popularity), 5)
+	return math.Round(score*100) / 100
+}
+
+func titleRelevance(query string, m Movie) int {
+	q := normalizeTitle(query)
+	if q == "" {
+		return 0
+	}
+	best := 0
+	for _, title := range []string{m.Title, m.OriginalTitle} {
+		t := normalizeTitle(title)
+		if t == "" {
+			continue
+		}
+		score := 0
+		switch {
+		case t == q:
+			score = 100
+		case strings.HasPrefix(t, q):
+			score = 80
+		case strings.Contains(" "+t, " "+q):
+			score = 60
+		case strings.Contains(t, q):
+			score = 40
+		default:
+			words := strings.Fields(q)
+			hits := 0
+			for _, w := range words {
+				if strings.Contains(t, w) {
+					hits++
+				}
+			}
+			if hits == len(words) {
+				score = 30
+			} else if hits > 0 {
+				score = 15 * hits / len(words)
+			}
+		}
+		best = max(best, score)
+	}
+	return best
+}
+
+func normalizeTitle(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r > 127:
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+type upcomingSessions struct {
+	count int
+	next  time.Time
+}
+
+func upcomingSessionsByMovie(repos Repositories) (map[int]upcomingSessions, error) {
+	sessions, err := repos.Sessions.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := map[int]upcomingSessions{}
+	for _, s := range sessions {
+		if s.MovieID == 0 || s.Status == SessionCancelled || !s.StartTime.After(now) {
+			continue
+		}
+		u := out[s.MovieID]
+		if u.count == 0 || s.StartTime.Before(u.next) {
+			u.next = s.StartTime
+		}
+		u.count++
+		out[s.MovieID] = u
+	}
+	return out, nil
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cinema/internal/service"
+)
+
+var ErrMovieNotFound = errors.New("movie not found")
+
+const maxCastMembers = 10
+
+var certAgeRe = regexp.MustCompile(`\d+`)
+
+var usCertificationAges = map[string]int{
+	"G":     0,
+	"PG":    6,
+	"PG-13": 13,
+	"R":     17,
+	"NC-17": 18,
+}
+
+func certificationMinAge(cert string) int {
+	if age, ok := usCertificationAges[cert]; ok {
+		return age
+	}
+	if n, err := strconv.Atoi(certAgeRe.FindString(cert)); err == nil {
+		return n
+	}
+	return 0
+}
+
+func MovieFromTMDB(t service.TMDBMovie) Movie {
+	m := Movie{
+		ID:            t.ID,
+		Title:         t.Title,
+		OriginalTitle: t.OriginalTitle,
+		Overview:      t.Overview,
+		PosterPath:    t.PosterPath,
+		BackdropPath:  t.BackdropPath,
+		ReleaseDate:   t.ReleaseDate,
+		VoteAverage:   t.VoteAverage,
+		Popularity:    t.Popularity,
+		Adult:         t.Adult,
+		Runtime:       t.Runtime,
+		AgeRating:     t.Certification(),
+	}
+	m.MinAge = certificationMinAge(m.AgeRating)
+	if t.Adult && m.MinAge < 18 {
+		m.MinAge = 18
+	}
+	for _, g := range t.Genres {
+		m.Genres = append(m.Genres, g.Name)
+	}
+	if len(t.Genres) == 0 {
+		for _, id := range t.GenreIDs {
+			if name := service.TMDBGenreName(id); name != "" {
+				m.Genres = append(m.Genres, name)
+			}
+		}
+	}
+	for _, v := range t.Videos.Results {
+		if v.Site != "YouTube" || (v.Type != "Trailer" && v.Type != "Teaser") {
+			continue
+		}
+		m.Trailers = append(m.Trailers, Trailer{
+			Name: v.Name,
+			Site: v.Site,
+			Key:  v.Key,
+			URL:  "https://www.youtube.com/watch?v=" + v.Key,
+		})
+	}
+	for _, c := range t.Credits.Cast {
+		if len(m.Cast) == maxCastMembers {
+			break
+		}
+		m.Cast = append(m.Cast, CastMember{Name: c.Name, Character: c.Character, ProfilePath: c.ProfilePath})
+	}
+	if t.PosterPath != "" {
+		m.Posters = map[string]string{}
+		for _, size := range service.TMDBPosterSizes {
+			m.Posters[size] = service.TMDBImageURL(size, t.PosterPath)
+		}
+	}
+	return m
+}
+
+func ImportMovie(repos Repositories, tmdbID int) (Movie, error) {
+	t, err := service.FetchTMDBMovie(tmdbID)
+	if err != nil {
+		return Movie{}, err
+	}
+	m := MovieFromTMDB(*t)
+	m.SyncedAt = time.Now()
+	if existing, ok, err := repos.Movies.GetByID(m.ID); err != nil {
+		return Movie{}, err
+	} else if ok {
+		m.ImportedAt = existing.ImportedAt
+	} else {
+		m.ImportedAt = m.SyncedAt
+	}
+	return repos.Movies.Upsert(m)
+}
+
+func ImportMovieBySearch(repos Repositories, query string) (Movie, error) {
+	results, err := service.SearchTMDBMovies(strings.TrimSpace(query))
+	if err != nil {
+		return Movie{}, err
+	}
+	if len(results) == 0 {
+		return Movie{}, ErrMovieNotFound
+	}
+	return ImportMovie(repos, results[0].ID)
+}
+
+func SyncMovies(repos Repositories) (int, error) {
+	movies, err := repos.Movies.List()
+	if err != nil {
+		return 0, err
+	}
+	synced := 0
+	for _, m := range movies {
+		if _, err := ImportMovie(repos, m.ID); err != nil {
+			log.Printf("[MOVIES] sync %d (%s) failed: %v", m.ID, m.Title, err)
+			continue
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+func StartMovieSync(repos Repositories, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := SyncMovies(repos)
+			if err != nil {
+				log.Println("[MOVIES] sync failed:", err)
+				continue
+			}
+			log.Printf("[MOVIES] refreshed %d catalog movies", n)
+		}
+	}()
+}
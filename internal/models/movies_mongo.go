@@ -0,0 +1,62 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoMovieRepository struct{}
+
+func NewMongoMovieRepository() MovieRepository {
+	return &mongoMovieRepository{}
+}
+
+func (r *mongoMovieRepository) Upsert(m Movie) (Movie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := service.MoviesCollection().ReplaceOne(ctx, bson.M{"id": m.ID}, m, options.Replace().SetUpsert(true))
+	if err != nil {
+		return Movie{}, err
+	}
+	return m, nil
+}
+
+func (r *mongoMovieRepository) GetByID(id int) (Movie, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var m Movie
+	err := service.MoviesCollection().FindOne(ctx, bson.M{"id": id}).Decode(&m)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return Movie{}, false, nil
+		}
+		return Movie{}, false, err
+	}
+	return m, true, nil
+}
+
+func (r *mongoMovieRepository) List() ([]Movie, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := service.MoviesCollection().Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"title": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	movies := []Movie{}
+	if err := cursor.All(ctx, &movies); err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
@@ -3,7 +3,6 @@ package models
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"cinema/internal/service"
@@ -14,7 +13,13 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func SaveOrderMongo(o Order) (Order, error) {
+type mongoOrderRepository struct{}
+
+func NewMongoOrderRepository() OrderRepository {
+	return &mongoOrderRepository{}
+}
+
+func (r *mongoOrderRepository) Save(o Order) (Order, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	result, err := service.OrdersCollection().InsertOne(ctx, o)
@@ -28,11 +33,11 @@ func SaveOrderMongo(o Order) (Order, error) {
 	return o, nil
 }
 
-func GetAllOrdersMongo() ([]Order, error) {
+func (r *mongoOrderRepository) GetAll() ([]Order, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	opts := options.Find().SetSort(bson.D{{Key: "id", Value: -1}})
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
 	cur, err := service.OrdersCollection().Find(ctx, bson.D{}, opts)
 	if err != nil {
 		return nil, err
@@ -50,7 +55,7 @@ func GetAllOrdersMongo() ([]Order, error) {
 	return out, cur.Err()
 }
 
-func GetOrderByIDMongo(id primitive.ObjectID) (*Order, bool, error) {
+func (r *mongoOrderRepository) GetByID(id primitive.ObjectID) (*Order, bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -66,30 +71,64 @@ func GetOrderByIDMongo(id primitive.ObjectID) (*Order, bool, error) {
 	return &o, true, nil
 }
 
-func MarkOrderPaidMongo(orderID primitive.ObjectID) error {
+func (r *mongoOrderRepository) GetByEmail(email string) ([]Order, error) {
+	orders := make([]Order, 0)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := service.OrdersCollection().UpdateOne(
+	// Orders placed before accounts were recorded on them only carry the
+	// contact email.
+	cursor, err := service.OrdersCollection().Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"user_email": email},
+		bson.M{"user_email": bson.M{"$in": bson.A{nil, ""}}, "customer_email": email},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err = cursor.All(ctx, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+func (r *mongoOrderRepository) UpdateStatus(orderID primitive.ObjectID, from string, to string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.OrdersCollection().UpdateOne(
 		ctx,
-		bson.M{"_id": orderID},
-		bson.M{"$set": bson.M{
-			"payment_status": "paid",
-			"paid_at":        time.Now(),
-		}},
+		bson.M{"_id": orderID, "payment_status": from},
+		bson.M{"$set": bson.M{"payment_status": to}},
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
 }
-func GetOrdersByEmailMongo(email string) ([]Order, error) {
-	var orders []Order
+
+func (r *mongoOrderRepository) CheckIn(orderID primitive.ObjectID, by string, at time.Time) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	collection := service.OrdersCollection()
+	res, err := service.OrdersCollection().UpdateOne(
+		ctx,
+		bson.M{"_id": orderID, "payment_status": "paid", "checked_in_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"checked_in_at": at, "checked_in_by": by}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *mongoOrderRepository) ListBySession(sessionID int) ([]Order, error) {
+	orders := make([]Order, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	filter := bson.M{"customer_email": email}
-	fmt.Printf("Search tickets: [%s]\n", email)
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := service.OrdersCollection().Find(ctx, bson.M{"session_id": sessionID})
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +137,51 @@ func GetOrdersByEmailMongo(email string) ([]Order, error) {
 	if err = cursor.All(ctx, &orders); err != nil {
 		return nil, err
 	}
-	fmt.Printf("Find tickets in database %d\n", len(orders))
 	return orders, nil
 }
+
+func (r *mongoOrderRepository) UpdateSessionDetails(sessionID int, cinema, hall string, start time.Time, changedAt time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.OrdersCollection().UpdateMany(
+		ctx,
+		bson.M{"session_id": sessionID, "payment_status": bson.M{"$in": []string{"paid", "reserved"}}},
+		bson.M{"$set": bson.M{
+			"cinema_name":        cinema,
+			"hall":               hall,
+			"start_time":         start,
+			"session_changed_at": changedAt,
+		}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(res.ModifiedCount), nil
+}
+
+func (r *mongoOrderRepository) MoveToSession(o Order) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.OrdersCollection().UpdateOne(
+		ctx,
+		bson.M{"_id": o.ID, "payment_status": "paid"},
+		bson.M{
+			"$set": bson.M{
+				"session_id":  o.SessionID,
+				"cinema_name": o.CinemaName,
+				"hall":        o.Hall,
+				"start_time":  o.StartTime,
+				"timezone":    o.Timezone,
+				"seat":        o.Seat,
+				"items":       o.Items,
+			},
+			"$unset": bson.M{"session_changed_at": ""},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
@@ -1,19 +1,56 @@
 package models
 
 import (
+	"errors"
+	"log"
 	"time"
 
+	"cinema/internal/service"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type PaymentStatus string
 
 const (
-	PaymentPending PaymentStatus = "pending"
-	PaymentPaid    PaymentStatus = "paid"
-	PaymentFailed  PaymentStatus = "failed"
+	PaymentPending      PaymentStatus = "pending"
+	PaymentPaid         PaymentStatus = "paid"
+	PaymentFailed       PaymentStatus = "failed"
+	PaymentRefunding    PaymentStatus = "refunding"
+	PaymentRefunded     PaymentStatus = "refunded"
+	PaymentRefundFailed PaymentStatus = "refund_failed"
 )
 
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentPending:      {PaymentPaid, PaymentFailed},
+	PaymentPaid:         {PaymentRefunding},
+	PaymentRefunding:    {PaymentRefunded, PaymentRefundFailed},
+	PaymentRefundFailed: {PaymentRefunding},
+}
+
+func (s PaymentStatus) CanTransition(to PaymentStatus) bool {
+	for _, next := range paymentTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func paymentSourcesFor(to PaymentStatus) []PaymentStatus {
+	out := make([]PaymentStatus, 0)
+	for from, targets := range paymentTransitions {
+		for _, t := range targets {
+			if t == to {
+				out = append(out, from)
+			}
+		}
+	}
+	return out
+}
+
+var ErrPaymentExists = errors.New("payment with this invoice already exists")
+
 type Payment struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	InvoiceID string             `bson:"invoice_id" json:"invoice_id"`
@@ -23,11 +60,102 @@ type Payment struct {
 	Currency string        `bson:"currency" json:"currency"`
 	Status   PaymentStatus `bson:"status" json:"status"`
 
-	EpayID    string    `bson:"epay_id,omitempty" json:"epay_id,omitempty"`
-	Callback  any       `bson:"callback,omitempty" json:"callback,omitempty"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	Provider    string    `bson:"provider,omitempty" json:"provider,omitempty"`
+	ProviderRef string    `bson:"epay_id,omitempty" json:"provider_ref,omitempty"`
+	Callback    any       `bson:"callback,omitempty" json:"callback,omitempty"`
+	CreatedAt   time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `bson:"updated_at" json:"updated_at"`
+	PaidAt      time.Time `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
+	RefundedAt  time.Time `bson:"refunded_at,omitempty" json:"refunded_at,omitempty"`
 
 	TerminalID string `bson:"terminal_id" json:"terminal_id"`
-	SecretHash string `bson:"secret_hash" json:"secret_hash"`
+	SecretHash string `bson:"secret_hash" json:"-"`
+}
+
+func CompletePayment(repos Repositories, p Payment, providerRef string, raw any) (bool, error) {
+	moved, err := repos.Payments.Transition(p.InvoiceID, PaymentPaid, providerRef, raw)
+	if err != nil || !moved {
+		return moved, err
+	}
+	paid, err := repos.Orders.UpdateStatus(p.OrderID, "reserved", "paid")
+	if err != nil {
+		log.Printf("[PAY] invoice %s captured but order %s was not updated, needs manual review: %v", p.InvoiceID, p.OrderID.Hex(), err)
+		return true, err
+	}
+	if !paid {
+		refundLateCapture(repos, p)
+		return true, nil
+	}
+	err = SellOrderHolds(repos, p.OrderID)
+	if errors.Is(err, ErrSeatLost) {
+		refundLostSeats(repos, p.OrderID, err)
+		return true, nil
+	}
+	if err != nil {
+		log.Println("[HOLDS] sell failed:", err)
+	}
+	if order, ok, err := repos.Orders.GetByID(p.OrderID); err == nil && ok {
+		if err := CreditOrderBonuses(repos, *order); err != nil {
+			log.Println("[BONUS] credit failed:", err)
+		}
+	}
+	return true, nil
+}
+
+// The order expired, was cancelled or failed before the provider confirmed the
+// capture. Its seats, promo and bonuses are already released, so the money goes back.
+func refundLateCapture(repos Repositories, p Payment) {
+	status := "missing"
+	if o, ok, err := repos.Orders.GetByID(p.OrderID); err == nil && ok {
+		status = o.PaymentStatus
+	}
+	fresh, ok, err := repos.Payments.GetByInvoice(p.InvoiceID)
+	if err == nil && !ok {
+		err = errors.New("payment not found")
+	}
+	if err == nil {
+		err = refundPayment(repos, fresh)
+	}
+	if err != nil {
+		log.Printf("[PAY] invoice %s paid for %s order %s; refund failed, needs manual review: %v", p.InvoiceID, status, p.OrderID.Hex(), err)
+		return
+	}
+	log.Printf("[PAY] invoice %s paid for %s order %s; refunded %.2f", p.InvoiceID, status, p.OrderID.Hex(), fresh.Amount)
+}
+
+func refundLostSeats(repos Repositories, orderID primitive.ObjectID, reason error) {
+	o, ok, err := repos.Orders.GetByID(orderID)
+	if err == nil && !ok {
+		err = errors.New("order not found")
+	}
+	var amount float64
+	if err == nil {
+		_, amount, err = CancelOrder(repos, *o, "Seat no longer available")
+	}
+	if err != nil {
+		log.Printf("[PAY] order %s: %v; refund failed, needs manual review: %v", orderID.Hex(), reason, err)
+		return
+	}
+	service.SendRefundNotification(o.CustomerEmail, o.MovieTitle, amount)
+}
+
+func FailPayment(repos Repositories, p Payment, raw any) (bool, error) {
+	moved, err := repos.Payments.Transition(p.InvoiceID, PaymentFailed, "", raw)
+	if err != nil || !moved {
+		return moved, err
+	}
+	FailOrder(repos, p.OrderID)
+	return true, nil
+}
+
+func FailOrder(repos Repositories, orderID primitive.ObjectID) {
+	if err := ReleaseOrderHolds(repos, orderID); err != nil {
+		log.Println("[HOLDS] release failed:", err)
+	}
+	if ok, _ := repos.Orders.UpdateStatus(orderID, "reserved", "failed"); ok {
+		_ = ReleaseOrderPromo(repos, orderID)
+		if err := ReverseOrderBonuses(repos, orderID, "Payment failed"); err != nil {
+			log.Println("[BONUS] reversal failed:", err)
+		}
+	}
 }
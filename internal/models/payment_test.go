@@ -0,0 +1,260 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeProvider records refunds instead of calling a real gateway.
+type fakeProvider struct {
+	mu         sync.Mutex
+	refunds    []string
+	failRefund bool
+}
+
+func (f *fakeProvider) Name() string { return "fake" }
+
+func (f *fakeProvider) Init(in service.PaymentInit) (*service.PaymentSession, error) {
+	return &service.PaymentSession{Provider: f.Name(), InvoiceID: in.InvoiceID}, nil
+}
+
+func (f *fakeProvider) ParseCallback(body []byte, failure bool) (service.PaymentCallback, error) {
+	return service.PaymentCallback{}, errors.New("not supported")
+}
+
+func (f *fakeProvider) VerifyCallback(cb service.PaymentCallback, secretHash string, amount float64) error {
+	return nil
+}
+
+func (f *fakeProvider) Status(invoiceID string) (service.ProviderPaymentStatus, error) {
+	return service.ProviderPaymentStatus{Status: service.ProviderStatusPending}, nil
+}
+
+func (f *fakeProvider) Refund(providerRef string, amount float64, currency string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failRefund {
+		return errInjected
+	}
+	f.refunds = append(f.refunds, providerRef)
+	return nil
+}
+
+func (f *fakeProvider) refundCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.refunds)
+}
+
+func newFakeProvider() *fakeProvider {
+	p := &fakeProvider{}
+	service.RegisterPaymentProvider(p)
+	return p
+}
+
+// newPendingPayment holds a seat, creates a reserved order for it and starts a
+// pending payment, like the booking and /pay/init handlers do.
+func newPendingPayment(t *testing.T, repos Repositories) (Order, Payment) {
+	t.Helper()
+	s := newHoldTestSession(t, repos)
+	_, holds, err := HoldSeats(repos, s.ID, []string{"A1"}, "buyer@example.com", time.Minute)
+	if err != nil {
+		t.Fatalf("hold: %v", err)
+	}
+	o, err := repos.Orders.Save(Order{
+		ID:            primitive.NewObjectID(),
+		CustomerEmail: "buyer@example.com",
+		SessionID:     s.ID,
+		Seat:          "A1",
+		FinalPrice:    2000,
+		BonusesEarned: 100,
+		PaymentStatus: "reserved",
+		HoldExpiresAt: holds[0].ExpiresAt,
+	})
+	if err != nil {
+		t.Fatalf("save order: %v", err)
+	}
+	if ok, err := repos.Holds.AttachOrder(holds[0].ID, o.ID); err != nil || !ok {
+		t.Fatalf("attach: ok=%v err=%v", ok, err)
+	}
+	p, err := repos.Payments.Create(Payment{
+		OrderID:   o.ID,
+		InvoiceID: "INV" + o.ID.Hex()[12:],
+		Amount:    o.FinalPrice,
+		Currency:  "KZT",
+		Provider:  "fake",
+	})
+	if err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+	return o, *p
+}
+
+func assertPaymentState(t *testing.T, repos Repositories, o Order, p Payment, order string, payment PaymentStatus) {
+	t.Helper()
+	gotOrder, ok, err := repos.Orders.GetByID(o.ID)
+	if err != nil || !ok {
+		t.Fatalf("get order: ok=%v err=%v", ok, err)
+	}
+	gotPayment, ok, err := repos.Payments.GetByInvoice(p.InvoiceID)
+	if err != nil || !ok {
+		t.Fatalf("get payment: ok=%v err=%v", ok, err)
+	}
+	if gotOrder.PaymentStatus != order || gotPayment.Status != payment {
+		t.Fatalf("order=%s payment=%s, want %s/%s", gotOrder.PaymentStatus, gotPayment.Status, order, payment)
+	}
+}
+
+func TestPaymentStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to PaymentStatus
+		want     bool
+	}{
+		{PaymentPending, PaymentPaid, true},
+		{PaymentPending, PaymentFailed, true},
+		{PaymentPending, PaymentRefunding, false},
+		{PaymentPaid, PaymentPaid, false},
+		{PaymentPaid, PaymentFailed, false},
+		{PaymentPaid, PaymentRefunding, true},
+		{PaymentFailed, PaymentPaid, false},
+		{PaymentRefunding, PaymentRefunded, true},
+		{PaymentRefunding, PaymentRefundFailed, true},
+		{PaymentRefundFailed, PaymentRefunding, true},
+		{PaymentRefunded, PaymentRefunding, false},
+	}
+	for _, tt := range tests {
+		if got := tt.from.CanTransition(tt.to); got != tt.want {
+			t.Errorf("%s -> %s = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestCompletePaymentSellsSeatsAndCreditsBonuses(t *testing.T) {
+	repos := NewMemoryRepositories()
+	newFakeProvider()
+	o, p := newPendingPayment(t, repos)
+
+	moved, err := CompletePayment(repos, p, "op-1", nil)
+	if err != nil || !moved {
+		t.Fatalf("CompletePayment: moved=%v err=%v", moved, err)
+	}
+	assertPaymentState(t, repos, o, p, "paid", PaymentPaid)
+
+	holds, _ := repos.Holds.GetByOrder(o.ID)
+	if len(holds) != 1 || holds[0].Status != HoldSold {
+		t.Fatalf("holds = %+v, want one sold hold", holds)
+	}
+	if n := countLedger(t, repos, o.ID, LedgerEarn); n != 1 {
+		t.Fatalf("earn entries = %d, want 1", n)
+	}
+}
+
+func TestCompletePaymentIgnoresDuplicateCallback(t *testing.T) {
+	repos := NewMemoryRepositories()
+	provider := newFakeProvider()
+	o, p := newPendingPayment(t, repos)
+
+	if moved, err := CompletePayment(repos, p, "op-1", nil); err != nil || !moved {
+		t.Fatalf("first callback: moved=%v err=%v", moved, err)
+	}
+	moved, err := CompletePayment(repos, p, "op-1", nil)
+	if err != nil || moved {
+		t.Fatalf("duplicate callback: moved=%v err=%v, want false/nil", moved, err)
+	}
+	assertPaymentState(t, repos, o, p, "paid", PaymentPaid)
+	if n := countLedger(t, repos, o.ID, LedgerEarn); n != 1 {
+		t.Fatalf("earn entries = %d, want 1", n)
+	}
+	if n := provider.refundCount(); n != 0 {
+		t.Fatalf("refunds = %d, want 0", n)
+	}
+}
+
+func TestCallbacksAfterFailureAreIgnored(t *testing.T) {
+	repos := NewMemoryRepositories()
+	newFakeProvider()
+	o, p := newPendingPayment(t, repos)
+
+	if moved, err := FailPayment(repos, p, nil); err != nil || !moved {
+		t.Fatalf("FailPayment: moved=%v err=%v", moved, err)
+	}
+	if moved, err := CompletePayment(repos, p, "op-1", nil); err != nil || moved {
+		t.Fatalf("success after failure: moved=%v err=%v, want false/nil", moved, err)
+	}
+	if moved, err := FailPayment(repos, p, nil); err != nil || moved {
+		t.Fatalf("duplicate failure: moved=%v err=%v, want false/nil", moved, err)
+	}
+	assertPaymentState(t, repos, o, p, "failed", PaymentFailed)
+	assertAvailable(t, repos, o.SessionID, "A1", "A2", "A3", "A4")
+}
+
+func TestFailureAfterSuccessIsIgnored(t *testing.T) {
+	repos := NewMemoryRepositories()
+	newFakeProvider()
+	o, p := newPendingPayment(t, repos)
+
+	if moved, err := CompletePayment(repos, p, "op-1", nil); err != nil || !moved {
+		t.Fatalf("CompletePayment: moved=%v err=%v", moved, err)
+	}
+	if moved, err := FailPayment(repos, p, nil); err != nil || moved {
+		t.Fatalf("failure after success: moved=%v err=%v, want false/nil", moved, err)
+	}
+	assertPaymentState(t, repos, o, p, "paid", PaymentPaid)
+}
+
+func TestCompletePaymentAfterExpiryRefunds(t *testing.T) {
+	repos := NewMemoryRepositories()
+	provider := newFakeProvider()
+	o, p := newPendingPayment(t, repos)
+	if ok, err := repos.Orders.UpdateStatus(o.ID, "reserved", "expired"); err != nil || !ok {
+		t.Fatalf("expire order: ok=%v err=%v", ok, err)
+	}
+
+	moved, err := CompletePayment(repos, p, "op-late", nil)
+	if err != nil || !moved {
+		t.Fatalf("late callback: moved=%v err=%v", moved, err)
+	}
+	assertPaymentState(t, repos, o, p, "expired", PaymentRefunded)
+	if n := provider.refundCount(); n != 1 {
+		t.Fatalf("refunds = %d, want 1", n)
+	}
+	if n := countLedger(t, repos, o.ID, LedgerEarn); n != 0 {
+		t.Fatalf("earn entries = %d, want 0", n)
+	}
+}
+
+func TestCompletePaymentAfterExpiryRecordsFailedRefund(t *testing.T) {
+	repos := NewMemoryRepositories()
+	provider := newFakeProvider()
+	provider.failRefund = true
+	o, p := newPendingPayment(t, repos)
+	if ok, err := repos.Orders.UpdateStatus(o.ID, "reserved", "expired"); err != nil || !ok {
+		t.Fatalf("expire order: ok=%v err=%v", ok, err)
+	}
+
+	if moved, err := CompletePayment(repos, p, "op-late", nil); err != nil || !moved {
+		t.Fatalf("late callback: moved=%v err=%v", moved, err)
+	}
+	assertPaymentState(t, repos, o, p, "expired", PaymentRefundFailed)
+}
+
+func countLedger(t *testing.T, repos Repositories, orderID primitive.ObjectID, typ LedgerEntryType) int {
+	t.Helper()
+	entries, err := repos.Ledger.ListByOrder(orderID)
+	if err != nil {
+		t.Fatalf("ledger: %v", err)
+	}
+	n := 0
+	for _, e := range entries {
+		if e.Type == typ {
+			n++
+		}
+	}
+	return n
+}
@@ -8,10 +8,18 @@ import (
 	"cinema/internal/service"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func CreatePaymentMongo(p Payment) (*Payment, error) {
+type mongoPaymentRepository struct{}
+
+func NewMongoPaymentRepository() PaymentRepository {
+	return &mongoPaymentRepository{}
+}
+
+func (r *mongoPaymentRepository) Create(p Payment) (*Payment, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -22,12 +30,15 @@ func CreatePaymentMongo(p Payment) (*Payment, error) {
 
 	_, err := service.PaymentsCollection().InsertOne(ctx, p)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrPaymentExists
+		}
 		return nil, err
 	}
 	return &p, nil
 }
 
-func GetPaymentByInvoiceMongo(invoiceID string) (*Payment, bool, error) {
+func (r *mongoPaymentRepository) GetByInvoice(invoiceID string) (*Payment, bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -45,36 +56,79 @@ func GetPaymentByInvoiceMongo(invoiceID string) (*Payment, bool, error) {
 	return &p, true, nil
 }
 
-func MarkPaymentPaidMongo(invoiceID string, epayID string, callback any) error {
+func (r *mongoPaymentRepository) ListByOrder(orderID primitive.ObjectID) ([]Payment, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	now := time.Now()
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cur, err := service.PaymentsCollection().Find(ctx, bson.M{"order_id": orderID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]Payment, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoPaymentRepository) find(filter bson.M) ([]Payment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	update := bson.M{
-		"$set": bson.M{
-			"status":   PaymentPaid,
-			"paid_at":  now,
-			"epay_id":  epayID,
-			"callback": callback,
-		},
+	opts := options.Find().SetSort(bson.M{"created_at": 1})
+	cur, err := service.PaymentsCollection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]Payment, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
 	}
+	return out, nil
+}
+
+func (r *mongoPaymentRepository) ListByStatus(status PaymentStatus, createdBefore time.Time) ([]Payment, error) {
+	return r.find(bson.M{"status": status, "created_at": bson.M{"$lt": createdBefore}})
+}
 
-	_, err := service.PaymentsCollection().UpdateOne(ctx, bson.M{"invoice_id": invoiceID}, update)
-	return err
+func (r *mongoPaymentRepository) ListCreatedBetween(from, to time.Time) ([]Payment, error) {
+	return r.find(bson.M{"created_at": bson.M{"$gte": from, "$lt": to}})
 }
 
-func MarkPaymentFailedMongo(invoiceID string, callback any) error {
+func (r *mongoPaymentRepository) Transition(invoiceID string, to PaymentStatus, providerRef string, callback any) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	update := bson.M{
-		"$set": bson.M{
-			"status":   PaymentFailed,
-			"callback": callback,
-		},
+	now := time.Now()
+	set := bson.M{
+		"status":     to,
+		"updated_at": now,
+	}
+	if callback != nil {
+		set["callback"] = callback
+	}
+	if providerRef != "" {
+		set["epay_id"] = providerRef
+	}
+	switch to {
+	case PaymentPaid:
+		set["paid_at"] = now
+	case PaymentRefunded:
+		set["refunded_at"] = now
 	}
 
-	_, err := service.PaymentsCollection().UpdateOne(ctx, bson.M{"invoice_id": invoiceID}, update)
-	return err
+	filter := bson.M{
+		"invoice_id": invoiceID,
+		"status":     bson.M{"$in": paymentSourcesFor(to)},
+	}
+	res, err := service.PaymentsCollection().UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
 }
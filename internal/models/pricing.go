@@ -0,0 +1,48 @@
+package models
+
+import (
+	"cinema/internal/service"
+)
+
+func ActivePricingRules(repo PricingRuleRepository) ([]service.PricingRule, error) {
+	rules, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]service.PricingRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Active {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func SeedPricingRules(repo PricingRuleRepository) error {
+	rules, err := repo.List()
+	if err != nil || len(rules) > 0 {
+		return err
+	}
+	for _, r := range service.DefaultPricingRules() {
+		if _, err := repo.Add(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func QuoteSeat(rules []service.PricingRule, s Session, h *Hall, seat string, age int, isStudent bool) OrderItem {
+	category := ""
+	if h != nil {
+		category = string(h.Category(seat))
+	}
+	q := service.QuotePrice(rules, service.PriceInput{
+		BasePrice:    s.BasePrice,
+		StartTime:    s.LocalStart(),
+		SeatCategory: category,
+		Format:       s.Format,
+		Age:          age,
+		IsStudent:    isStudent,
+	})
+	return OrderItem{Seat: seat, Category: category, Price: q.FinalPrice, Breakdown: q.Lines}
+}
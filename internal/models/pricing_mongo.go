@@ -0,0 +1,80 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoPricingRuleRepository struct{}
+
+func NewMongoPricingRuleRepository() PricingRuleRepository {
+	return &mongoPricingRuleRepository{}
+}
+
+func (r *mongoPricingRuleRepository) List() ([]service.PricingRule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: 1}, {Key: "id", Value: 1}})
+	cur, err := service.PricingRulesCollection().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]service.PricingRule, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoPricingRuleRepository) Add(rule service.PricingRule) (service.PricingRule, error) {
+	id, err := nextID("pricing_rules")
+	if err != nil {
+		return service.PricingRule{}, err
+	}
+	rule.ID = id
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := service.PricingRulesCollection().InsertOne(ctx, rule); err != nil {
+		return service.PricingRule{}, err
+	}
+	return rule, nil
+}
+
+func (r *mongoPricingRuleRepository) Update(rule service.PricingRule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.PricingRulesCollection().ReplaceOne(ctx, bson.M{"id": rule.ID}, rule)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("rule not found")
+	}
+	return nil
+}
+
+func (r *mongoPricingRuleRepository) Delete(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.PricingRulesCollection().DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errors.New("rule not found")
+	}
+	return nil
+}
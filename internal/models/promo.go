@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PromoKind string
+
+const (
+	PromoPercent PromoKind = "percent"
+	PromoFixed   PromoKind = "fixed"
+)
+
+type PromoRedemption struct {
+	Email      string             `bson:"email" json:"email"`
+	OrderID    primitive.ObjectID `bson:"order_id" json:"order_id"`
+	RedeemedAt time.Time          `bson:"redeemed_at" json:"redeemed_at"`
+}
+
+type PromoCode struct {
+	Code   string    `bson:"code" json:"code"`
+	Kind   PromoKind `bson:"kind" json:"kind"`
+	Value  float64   `bson:"value" json:"value"`
+	Active bool      `bson:"active" json:"active"`
+
+	ValidFrom  time.Time `bson:"valid_from,omitempty" json:"valid_from,omitempty"`
+	ValidUntil time.Time `bson:"valid_until,omitempty" json:"valid_until,omitempty"`
+
+	MaxUses        int `bson:"max_uses" json:"max_uses"`
+	MaxUsesPerUser int `bson:"max_uses_per_user" json:"max_uses_per_user"`
+	UsedCount      int `bson:"used_count" json:"used_count"`
+
+	MovieIDs []int    `bson:"movie_ids,omitempty" json:"movie_ids,omitempty"`
+	Cinemas  []string `bson:"cinemas,omitempty" json:"cinemas,omitempty"`
+	MinSpend float64  `bson:"min_spend,omitempty" json:"min_spend,omitempty"`
+
+	Redemptions []PromoRedemption `bson:"redemptions" json:"redemptions,omitempty"`
+	CreatedAt   time.Time         `bson:"created_at" json:"created_at"`
+}
+
+var (
+	ErrPromoNotFound     = errors.New("promo code not found")
+	ErrPromoExists       = errors.New("promo code already exists")
+	ErrPromoInactive     = errors.New("promo code is not active")
+	ErrPromoExhausted    = errors.New("promo code usage limit reached")
+	ErrPromoNotForMovie  = errors.New("promo code is not valid for this movie")
+	ErrPromoNotForCinema = errors.New("promo code is not valid for this cinema")
+)
+
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p PromoCode) Validate() error {
+	if p.Code == "" {
+		return errors.New("code is required")
+	}
+	switch p.Kind {
+	case PromoPercent:
+		if p.Value <= 0 || p.Value > 100 {
+			return errors.New("percent value must be in (0, 100]")
+		}
+	case PromoFixed:
+		if p.Value <= 0 {
+			return errors.New("fixed value must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown promo kind %q", p.Kind)
+	}
+	if p.MaxUses < 0 || p.MaxUsesPerUser < 0 {
+		return errors.New("usage limits cannot be negative")
+	}
+	if !p.ValidFrom.IsZero() && !p.ValidUntil.IsZero() && !p.ValidFrom.Before(p.ValidUntil) {
+		return errors.New("valid_from must be before valid_until")
+	}
+	return nil
+}
+
+func (p PromoCode) usesBy(email string) int {
+	n := 0
+	for _, r := range p.Redemptions {
+		if r.Email == email {
+			n++
+		}
+	}
+	return n
+}
+
+func (p PromoCode) Check(now time.Time, email string, s Session, subtotal float64) error {
+	if !p.Active {
+		return ErrPromoInactive
+	}
+	if !p.ValidFrom.IsZero() && now.Before(p.ValidFrom) {
+		return ErrPromoInactive
+	}
+	if !p.ValidUntil.IsZero() && !now.Before(p.ValidUntil) {
+		return ErrPromoInactive
+	}
+	if p.MaxUses > 0 && p.UsedCount >= p.MaxUses {
+		return ErrPromoExhausted
+	}
+	if p.MaxUsesPerUser > 0 && p.usesBy(email) >= p.MaxUsesPerUser {
+		return ErrPromoExhausted
+	}
+	if len(p.MovieIDs) > 0 {
+		found := false
+		for _, id := range p.MovieIDs {
+			if id == s.MovieID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrPromoNotForMovie
+		}
+	}
+	if len(p.Cinemas) > 0 {
+		found := false
+		for _, c := range p.Cinemas {
+			if c == s.CinemaName {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrPromoNotForCinema
+		}
+	}
+	if p.MinSpend > 0 && subtotal < p.MinSpend {
+		return fmt.Errorf("promo code requires a minimum spend of %.0f", p.MinSpend)
+	}
+	return nil
+}
+
+func (p PromoCode) Discount(subtotal float64) float64 {
+	d := p.Value
+	if p.Kind == PromoPercent {
+		d = subtotal * p.Value / 100
+	}
+	d = math.Round(d*100) / 100
+	if d > subtotal {
+		d = subtotal
+	}
+	return d
+}
+
+func ReleaseOrderPromo(repos Repositories, orderID primitive.ObjectID) error {
+	o, ok, err := repos.Orders.GetByID(orderID)
+	if err != nil || !ok || o.AppliedPromo == "" {
+		return err
+	}
+	return repos.Promos.Release(o.AppliedPromo, orderID)
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoPromoRepository struct{}
+
+func NewMongoPromoRepository() PromoRepository {
+	return &mongoPromoRepository{}
+}
+
+func (r *mongoPromoRepository) Create(p PromoCode) (PromoCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p.CreatedAt = time.Now()
+	p.UsedCount = 0
+	p.Redemptions = []PromoRedemption{}
+
+	count, err := service.PromoCodesCollection().CountDocuments(ctx, bson.M{"code": p.Code})
+	if err != nil {
+		return PromoCode{}, err
+	}
+	if count > 0 {
+		return PromoCode{}, ErrPromoExists
+	}
+
+	if _, err := service.PromoCodesCollection().InsertOne(ctx, p); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return PromoCode{}, ErrPromoExists
+		}
+		return PromoCode{}, err
+	}
+	return p, nil
+}
+
+func (r *mongoPromoRepository) Get(code string) (PromoCode, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var p PromoCode
+	err := service.PromoCodesCollection().FindOne(ctx, bson.M{"code": code}).Decode(&p)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return PromoCode{}, false, nil
+		}
+		return PromoCode{}, false, err
+	}
+	return p, true, nil
+}
+
+func (r *mongoPromoRepository) List() ([]PromoCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cur, err := service.PromoCodesCollection().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]PromoCode, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (r *mongoPromoRepository) SetActive(code string, active bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.PromoCodesCollection().UpdateOne(ctx,
+		bson.M{"code": code},
+		bson.M{"$set": bson.M{"active": active}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrPromoNotFound
+	}
+	return nil
+}
+
+func (r *mongoPromoRepository) Redeem(code string, email string, orderID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"code":   code,
+		"active": true,
+		"$expr": bson.M{"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"$eq": bson.A{"$max_uses", 0}},
+				bson.M{"$lt": bson.A{"$used_count", "$max_uses"}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"$eq": bson.A{"$max_uses_per_user", 0}},
+				bson.M{"$lt": bson.A{
+					bson.M{"$size": bson.M{"$filter": bson.M{
+						"input": bson.M{"$ifNull": bson.A{"$redemptions", bson.A{}}},
+						"cond":  bson.M{"$eq": bson.A{"$$this.email", email}},
+					}}},
+					"$max_uses_per_user",
+				}},
+			}},
+		}},
+	}
+	update := bson.M{
+		"$inc":  bson.M{"used_count": 1},
+		"$push": bson.M{"redemptions": PromoRedemption{Email: email, OrderID: orderID, RedeemedAt: time.Now()}},
+	}
+
+	res, err := service.PromoCodesCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount == 0 {
+		return ErrPromoExhausted
+	}
+	return nil
+}
+
+func (r *mongoPromoRepository) Release(code string, orderID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := service.PromoCodesCollection().UpdateOne(ctx,
+		bson.M{"code": code, "redemptions.order_id": orderID},
+		bson.M{
+			"$inc":  bson.M{"used_count": -1},
+			"$pull": bson.M{"redemptions": bson.M{"order_id": orderID}},
+		},
+	)
+	return err
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTestPromo(t *testing.T, repos Repositories, maxUses, maxPerUser int) PromoCode {
+	t.Helper()
+	p, err := repos.Promos.Create(PromoCode{
+		Code:           "RUSH",
+		Kind:           PromoPercent,
+		Value:          10,
+		Active:         true,
+		MaxUses:        maxUses,
+		MaxUsesPerUser: maxPerUser,
+	})
+	if err != nil {
+		t.Fatalf("create promo: %v", err)
+	}
+	return p
+}
+
+// redeemConcurrently redeems code once per email at the same time and returns
+// how many redemptions went through.
+func redeemConcurrently(t *testing.T, repos Repositories, code string, emails []string) int {
+	t.Helper()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	succeeded := 0
+	for _, email := range emails {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repos.Promos.Redeem(code, email, primitive.NewObjectID())
+			if err != nil && !errors.Is(err, ErrPromoExhausted) {
+				t.Errorf("Redeem(%s): %v", email, err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return succeeded
+}
+
+func TestRedeemConcurrentRespectsMaxUses(t *testing.T) {
+	repos := NewMemoryRepositories()
+	p := newTestPromo(t, repos, 3, 0)
+
+	emails := make([]string, 20)
+	for i := range emails {
+		emails[i] = fmt.Sprintf("user%d@example.com", i)
+	}
+	if n := redeemConcurrently(t, repos, p.Code, emails); n != 3 {
+		t.Fatalf("redemptions = %d, want 3", n)
+	}
+
+	got, _, _ := repos.Promos.Get(p.Code)
+	if got.UsedCount != 3 || len(got.Redemptions) != 3 {
+		t.Fatalf("used_count = %d, redemptions = %d, want 3/3", got.UsedCount, len(got.Redemptions))
+	}
+}
+
+func TestRedeemConcurrentRespectsPerUserLimit(t *testing.T) {
+	repos := NewMemoryRepositories()
+	p := newTestPromo(t, repos, 0, 1)
+
+	emails := make([]string, 10)
+	for i := range emails {
+		emails[i] = "same@example.com"
+	}
+	if n := redeemConcurrently(t, repos, p.Code, emails); n != 1 {
+		t.Fatalf("redemptions = %d, want 1", n)
+	}
+}
+
+func TestReleaseFreesPromoUse(t *testing.T) {
+	repos := NewMemoryRepositories()
+	p := newTestPromo(t, repos, 1, 0)
+
+	order := primitive.NewObjectID()
+	if err := repos.Promos.Redeem(p.Code, "a@example.com", order); err != nil {
+		t.Fatalf("first redeem: %v", err)
+	}
+	if err := repos.Promos.Redeem(p.Code, "b@example.com", primitive.NewObjectID()); !errors.Is(err, ErrPromoExhausted) {
+		t.Fatalf("second redeem err = %v, want ErrPromoExhausted", err)
+	}
+	if err := repos.Promos.Release(p.Code, order); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if err := repos.Promos.Redeem(p.Code, "b@example.com", primitive.NewObjectID()); err != nil {
+		t.Fatalf("redeem after release: %v", err)
+	}
+}
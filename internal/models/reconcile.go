@@ -0,0 +1,215 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReconciliationMismatch struct {
+	InvoiceID    string             `bson:"invoice_id" json:"invoice_id"`
+	OrderID      primitive.ObjectID `bson:"order_id" json:"order_id"`
+	Provider     string             `bson:"provider" json:"provider"`
+	Issue        string             `bson:"issue" json:"issue"`
+	LocalStatus  PaymentStatus      `bson:"local_status" json:"local_status"`
+	RemoteStatus string             `bson:"remote_status,omitempty" json:"remote_status,omitempty"`
+	OrderStatus  string             `bson:"order_status,omitempty" json:"order_status,omitempty"`
+	Amount       float64            `bson:"amount" json:"amount"`
+	RemoteAmount float64            `bson:"remote_amount,omitempty" json:"remote_amount,omitempty"`
+}
+
+type ReconciliationReport struct {
+	ID         primitive.ObjectID       `bson:"_id,omitempty" json:"id"`
+	Date       string                   `bson:"date" json:"date"`
+	Checked    int                      `bson:"checked" json:"checked"`
+	Matched    int                      `bson:"matched" json:"matched"`
+	Mismatches []ReconciliationMismatch `bson:"mismatches" json:"mismatches"`
+	Errors     []string                 `bson:"errors,omitempty" json:"errors,omitempty"`
+	CreatedAt  time.Time                `bson:"created_at" json:"created_at"`
+}
+
+var expectedRemoteStatus = map[PaymentStatus][]string{
+	PaymentPending:      {service.ProviderStatusPending},
+	PaymentPaid:         {service.ProviderStatusPaid},
+	PaymentFailed:       {service.ProviderStatusFailed, service.ProviderStatusPending},
+	PaymentRefunding:    {service.ProviderStatusPaid, service.ProviderStatusRefunded},
+	PaymentRefunded:     {service.ProviderStatusRefunded},
+	PaymentRefundFailed: {service.ProviderStatusPaid},
+}
+
+var expectedOrderStatus = map[PaymentStatus][]string{
+	PaymentPaid:         {"paid"},
+	PaymentRefunding:    {"paid", "refunding"},
+	PaymentRefundFailed: {"paid"},
+	PaymentRefunded:     {"refunded", "expired", "cancelled", "failed"},
+}
+
+func ReconcilePayment(repos Repositories, p Payment) (bool, error) {
+	provider, err := service.PaymentProviderByName(p.Provider)
+	if err != nil {
+		return false, err
+	}
+	remote, err := provider.Status(p.InvoiceID)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case p.Status == PaymentPending && remote.Status == service.ProviderStatusPaid:
+		if math.Abs(remote.Amount-p.Amount) > 0.01 {
+			return false, fmt.Errorf("invoice %s: provider amount %.2f does not match %.2f", p.InvoiceID, remote.Amount, p.Amount)
+		}
+		return CompletePayment(repos, p, remote.ProviderRef, remote.Raw)
+	case p.Status == PaymentPending && remote.Status == service.ProviderStatusFailed:
+		return FailPayment(repos, p, remote.Raw)
+	case p.Status == PaymentRefunding && remote.Status == service.ProviderStatusRefunded:
+		moved, err := repos.Payments.Transition(p.InvoiceID, PaymentRefunded, "", nil)
+		if err != nil || !moved {
+			return moved, err
+		}
+		_, err = repos.Orders.UpdateStatus(p.OrderID, "refunding", "refunded")
+		return true, err
+	}
+	return false, nil
+}
+
+func ReconcilePendingPayments(repos Repositories, olderThan time.Time) (int, error) {
+	updated := 0
+	for _, status := range []PaymentStatus{PaymentPending, PaymentRefunding} {
+		payments, err := repos.Payments.ListByStatus(status, olderThan)
+		if err != nil {
+			return updated, err
+		}
+		for _, p := range payments {
+			changed, err := ReconcilePayment(repos, p)
+			if err != nil {
+				log.Printf("[RECONCILE] invoice %s: %v", p.InvoiceID, err)
+				continue
+			}
+			if changed {
+				updated++
+			}
+		}
+	}
+	return updated, nil
+}
+
+func BuildReconciliationReport(repos Repositories, day time.Time) (ReconciliationReport, error) {
+	loc := service.DefaultLocation()
+	from := time.Date(day.In(loc).Year(), day.In(loc).Month(), day.In(loc).Day(), 0, 0, 0, 0, loc)
+	to := from.AddDate(0, 0, 1)
+
+	rep := ReconciliationReport{
+		Date:       from.Format("2006-01-02"),
+		Mismatches: make([]ReconciliationMismatch, 0),
+	}
+	payments, err := repos.Payments.ListCreatedBetween(from, to)
+	if err != nil {
+		return rep, err
+	}
+
+	for _, p := range payments {
+		rep.Checked++
+		m := ReconciliationMismatch{
+			InvoiceID:   p.InvoiceID,
+			OrderID:     p.OrderID,
+			Provider:    p.Provider,
+			LocalStatus: p.Status,
+			Amount:      p.Amount,
+		}
+		if m.Provider == "" {
+			m.Provider = "epay"
+		}
+
+		provider, err := service.PaymentProviderByName(p.Provider)
+		if err != nil {
+			rep.Errors = append(rep.Errors, fmt.Sprintf("%s: %v", p.InvoiceID, err))
+			continue
+		}
+		remote, err := provider.Status(p.InvoiceID)
+		if err != nil {
+			rep.Errors = append(rep.Errors, fmt.Sprintf("%s: %v", p.InvoiceID, err))
+			continue
+		}
+		m.RemoteStatus = remote.Status
+		m.RemoteAmount = remote.Amount
+
+		if order, ok, err := repos.Orders.GetByID(p.OrderID); err == nil && ok {
+			m.OrderStatus = order.PaymentStatus
+		}
+
+		switch {
+		case !statusIn(remote.Status, expectedRemoteStatus[p.Status]):
+			m.Issue = "status_mismatch"
+		case remote.Status != service.ProviderStatusPending && remote.Amount > 0 && math.Abs(remote.Amount-p.Amount) > 0.01:
+			m.Issue = "amount_mismatch"
+		case !orderStatusMatches(p.Status, m.OrderStatus):
+			m.Issue = "order_mismatch"
+		}
+		if m.Issue == "" {
+			rep.Matched++
+			continue
+		}
+		rep.Mismatches = append(rep.Mismatches, m)
+	}
+	return rep, nil
+}
+
+func orderStatusMatches(status PaymentStatus, orderStatus string) bool {
+	expected, ok := expectedOrderStatus[status]
+	return !ok || statusIn(orderStatus, expected)
+}
+
+func statusIn(status string, allowed []string) bool {
+	for _, s := range allowed {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func StartPaymentReconciler(repos Repositories, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := ReconcilePendingPayments(repos, time.Now().Add(-service.PaymentReconcileAfter()))
+			if err != nil {
+				log.Println("[RECONCILE] sweep failed:", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[RECONCILE] updated %d stale payments", n)
+			}
+		}
+	}()
+}
+
+func StartDailyReconciliationReport(repos Repositories, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			yesterday := time.Now().In(service.DefaultLocation()).AddDate(0, 0, -1)
+			if _, ok, err := repos.Reconciliation.GetByDate(yesterday.Format("2006-01-02")); err != nil || ok {
+				continue
+			}
+			rep, err := BuildReconciliationReport(repos, yesterday)
+			if err != nil {
+				log.Println("[RECONCILE] report failed:", err)
+				continue
+			}
+			if _, err := repos.Reconciliation.Save(rep); err != nil {
+				log.Println("[RECONCILE] report save failed:", err)
+				continue
+			}
+			log.Printf("[RECONCILE] report %s: %d checked, %d mismatches", rep.Date, rep.Checked, len(rep.Mismatches))
+		}
+	}()
+}
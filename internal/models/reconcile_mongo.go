@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoReconciliationRepository struct{}
+
+func NewMongoReconciliationRepository() ReconciliationRepository {
+	return &mongoReconciliationRepository{}
+}
+
+func (r *mongoReconciliationRepository) Save(rep ReconciliationReport) (ReconciliationReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rep.CreatedAt = time.Now()
+	doc := bson.M{
+		"date":       rep.Date,
+		"checked":    rep.Checked,
+		"matched":    rep.Matched,
+		"mismatches": rep.Mismatches,
+		"errors":     rep.Errors,
+		"created_at": rep.CreatedAt,
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := service.ReconciliationReportsCollection().
+		FindOneAndUpdate(ctx, bson.M{"date": rep.Date}, bson.M{"$set": doc}, opts).
+		Decode(&rep)
+	if err != nil {
+		return ReconciliationReport{}, err
+	}
+	return rep, nil
+}
+
+func (r *mongoReconciliationRepository) GetByDate(date string) (ReconciliationReport, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var rep ReconciliationReport
+	err := service.ReconciliationReportsCollection().FindOne(ctx, bson.M{"date": date}).Decode(&rep)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ReconciliationReport{}, false, nil
+		}
+		return ReconciliationReport{}, false, err
+	}
+	return rep, true, nil
+}
+
+func (r *mongoReconciliationRepository) List() ([]ReconciliationReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"date": -1})
+	cur, err := service.ReconciliationReportsCollection().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	out := make([]ReconciliationReport, 0)
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"log"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrOrderNotCancellable = errors.New("order cannot be cancelled")
+	ErrRefundInProgress    = errors.New("refund already in progress")
+)
+
+func paidPayment(repos Repositories, orderID primitive.ObjectID) (*Payment, error) {
+	payments, err := repos.Payments.ListByOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		switch p.Status {
+		case PaymentPaid, PaymentRefundFailed, PaymentRefunding:
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func refundPayment(repos Repositories, p *Payment) error {
+	provider, err := service.PaymentProviderByName(p.Provider)
+	if err != nil {
+		return err
+	}
+	ok, err := repos.Payments.Transition(p.InvoiceID, PaymentRefunding, "", nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRefundInProgress
+	}
+	if err := provider.Refund(p.ProviderRef, p.Amount, p.Currency); err != nil {
+		if _, terr := repos.Payments.Transition(p.InvoiceID, PaymentRefundFailed, "", nil); terr != nil {
+			log.Println("[REFUND] status update failed:", terr)
+		}
+		return err
+	}
+	_, err = repos.Payments.Transition(p.InvoiceID, PaymentRefunded, "", nil)
+	return err
+}
+
+func CancelOrder(repos Repositories, o Order, note string) (Order, float64, error) {
+	refunded := 0.0
+	switch o.PaymentStatus {
+	case "reserved":
+		ok, err := repos.Orders.UpdateStatus(o.ID, "reserved", "cancelled")
+		if err != nil {
+			return o, 0, err
+		}
+		if !ok {
+			return o, 0, ErrOrderNotCancellable
+		}
+		o.PaymentStatus = "cancelled"
+	case "paid":
+		p, err := paidPayment(repos, o.ID)
+		if err != nil {
+			return o, 0, err
+		}
+		ok, err := repos.Orders.UpdateStatus(o.ID, "paid", "refunding")
+		if err != nil {
+			return o, 0, err
+		}
+		if !ok {
+			return o, 0, ErrOrderNotCancellable
+		}
+		if p != nil {
+			if err := refundPayment(repos, p); err != nil {
+				if _, rerr := repos.Orders.UpdateStatus(o.ID, "refunding", "paid"); rerr != nil {
+					log.Printf("[REFUND] order %s stuck in refunding: %v", o.ID.Hex(), rerr)
+				}
+				return o, 0, err
+			}
+			refunded = p.Amount
+		}
+		if _, err := repos.Orders.UpdateStatus(o.ID, "refunding", "refunded"); err != nil {
+			log.Printf("[REFUND] order %s refunded but status not updated: %v", o.ID.Hex(), err)
+		}
+		o.PaymentStatus = "refunded"
+	case "refunding":
+		return o, 0, ErrRefundInProgress
+	default:
+		return o, 0, ErrOrderNotCancellable
+	}
+
+	if err := ReturnOrderSeats(repos, o.ID); err != nil {
+		log.Println("[HOLDS] return failed:", err)
+	}
+	if err := ReleaseOrderPromo(repos, o.ID); err != nil {
+		log.Println("[PROMO] release failed:", err)
+	}
+	if err := ReverseOrderBonuses(repos, o.ID, note); err != nil {
+		log.Println("[BONUS] reversal failed:", err)
+	}
+	return o, refunded, nil
+}
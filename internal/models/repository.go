@@ -0,0 +1,206 @@
+package models
+
+import (
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionRepository interface {
+	Add(s Session) (Session, error)
+	GetAll() ([]Session, error)
+	GetByID(id int) (Session, bool, error)
+	Filter(f SessionFilter) ([]Session, error)
+	ReserveSeats(sessionID int, seats []string) (Session, error)
+	ReleaseSeat(sessionID int, seat string) error
+	Update(current Session, updated Session) (bool, error)
+	Cancel(id int, reason string, at time.Time) (bool, error)
+}
+
+type MovieRepository interface {
+	Upsert(m Movie) (Movie, error)
+	GetByID(id int) (Movie, bool, error)
+	List() ([]Movie, error)
+}
+
+type OrderRepository interface {
+	Save(o Order) (Order, error)
+	GetAll() ([]Order, error)
+	GetByID(id primitive.ObjectID) (*Order, bool, error)
+	GetByEmail(email string) ([]Order, error)
+	UpdateStatus(orderID primitive.ObjectID, from string, to string) (bool, error)
+	CheckIn(orderID primitive.ObjectID, by string, at time.Time) (bool, error)
+	ListBySession(sessionID int) ([]Order, error)
+	UpdateSessionDetails(sessionID int, cinema, hall string, start time.Time, changedAt time.Time) (int, error)
+	MoveToSession(o Order) (bool, error)
+}
+
+type PaymentRepository interface {
+	Create(p Payment) (*Payment, error)
+	GetByInvoice(invoiceID string) (*Payment, bool, error)
+	ListByOrder(orderID primitive.ObjectID) ([]Payment, error)
+	ListByStatus(status PaymentStatus, createdBefore time.Time) ([]Payment, error)
+	ListCreatedBetween(from, to time.Time) ([]Payment, error)
+	Transition(invoiceID string, to PaymentStatus, providerRef string, callback any) (bool, error)
+}
+
+type UserRepository interface {
+	Create(u User) error
+	GetByEmail(email string) (User, bool, error)
+	BumpTokenVersion(email string) (int, error)
+	MarkEmailVerified(email string) error
+	UpdatePassword(email string, hash string) error
+	List() ([]User, error)
+	SetRole(email string, role string, cinemas []string) error
+	SetTwoFactor(email string, tf TwoFactor) error
+	UseTOTPStep(email string, step int64) (bool, error)
+	UseRecoveryCode(email string, hash string) (bool, error)
+}
+
+type ActionTokenRepository interface {
+	Create(t ActionToken) error
+	Consume(jti string, purpose string) (bool, error)
+}
+
+type LoginAttemptRepository interface {
+	Get(key string) (LoginAttempt, bool, error)
+	RecordFailure(key string, now time.Time, window time.Duration) (LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Reset(key string) error
+}
+
+type RefreshTokenRepository interface {
+	Create(t RefreshToken) (RefreshToken, error)
+	GetByHash(hash string) (RefreshToken, bool, error)
+	MarkUsed(id primitive.ObjectID, replacedBy primitive.ObjectID) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(email string) error
+}
+
+type RevokedTokenRepository interface {
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
+type HoldRepository interface {
+	Create(h SeatHold) (SeatHold, error)
+	GetActive(sessionID int, seat string) (SeatHold, bool, error)
+	GetByOrder(orderID primitive.ObjectID) ([]SeatHold, error)
+	AttachOrder(id primitive.ObjectID, orderID primitive.ObjectID) (bool, error)
+	Extend(id primitive.ObjectID, expiresAt time.Time) error
+	Transition(id primitive.ObjectID, from, to HoldStatus) (bool, error)
+	ListExpired(now time.Time) ([]SeatHold, error)
+	ListActiveBySession(sessionID int) ([]SeatHold, error)
+}
+
+type HallRepository interface {
+	Add(h Hall) (Hall, error)
+	GetByID(id int) (Hall, bool, error)
+	List(cinema string) ([]Hall, error)
+}
+
+type CinemaRepository interface {
+	Create(c Cinema) (Cinema, error)
+	GetByID(id int) (Cinema, bool, error)
+	GetByName(name string) (Cinema, bool, error)
+	List(city string) ([]Cinema, error)
+	Update(c Cinema) (bool, error)
+	Delete(id int) (bool, error)
+}
+
+type PricingRuleRepository interface {
+	List() ([]service.PricingRule, error)
+	Add(rule service.PricingRule) (service.PricingRule, error)
+	Update(rule service.PricingRule) error
+	Delete(id int) error
+}
+
+type PromoRepository interface {
+	Create(p PromoCode) (PromoCode, error)
+	Get(code string) (PromoCode, bool, error)
+	List() ([]PromoCode, error)
+	SetActive(code string, active bool) error
+	Redeem(code string, email string, orderID primitive.ObjectID) error
+	Release(code string, orderID primitive.ObjectID) error
+}
+
+type LedgerRepository interface {
+	Append(e LedgerEntry) (LedgerEntry, error)
+	Spend(email string, amount int, orderID primitive.ObjectID) (LedgerEntry, error)
+	Balance(email string) (int, error)
+	History(email string) ([]LedgerEntry, error)
+	ListByOrder(orderID primitive.ObjectID) ([]LedgerEntry, error)
+	AccountsWithExpiredEarnings(now time.Time) ([]string, error)
+}
+
+type ReconciliationRepository interface {
+	Save(r ReconciliationReport) (ReconciliationReport, error)
+	GetByDate(date string) (ReconciliationReport, bool, error)
+	List() ([]ReconciliationReport, error)
+}
+
+type Repositories struct {
+	Sessions SessionRepository
+	Orders   OrderRepository
+	Payments PaymentRepository
+	Users    UserRepository
+	Holds    HoldRepository
+	Halls    HallRepository
+	Pricing  PricingRuleRepository
+	Promos   PromoRepository
+	Ledger   LedgerRepository
+
+	Reconciliation ReconciliationRepository
+	RefreshTokens  RefreshTokenRepository
+	RevokedTokens  RevokedTokenRepository
+	ActionTokens   ActionTokenRepository
+	LoginAttempts  LoginAttemptRepository
+	Movies         MovieRepository
+	Cinemas        CinemaRepository
+}
+
+func NewMongoRepositories() Repositories {
+	return Repositories{
+		Sessions: NewMongoSessionRepository(),
+		Orders:   NewMongoOrderRepository(),
+		Payments: NewMongoPaymentRepository(),
+		Users:    NewMongoUserRepository(),
+		Holds:    NewMongoHoldRepository(),
+		Halls:    NewMongoHallRepository(),
+		Pricing:  NewMongoPricingRuleRepository(),
+		Promos:   NewMongoPromoRepository(),
+		Ledger:   NewMongoLedgerRepository(),
+
+		Reconciliation: NewMongoReconciliationRepository(),
+		RefreshTokens:  NewMongoRefreshTokenRepository(),
+		RevokedTokens:  NewMongoRevokedTokenRepository(),
+		ActionTokens:   NewMongoActionTokenRepository(),
+		LoginAttempts:  NewLoginAttemptRepository(),
+		Movies:         NewMongoMovieRepository(),
+		Cinemas:        NewMongoCinemaRepository(),
+	}
+}
+
+func NewMemoryRepositories() Repositories {
+	return Repositories{
+		Sessions: NewMemorySessionRepository(),
+		Orders:   NewMemoryOrderRepository(),
+		Payments: NewMemoryPaymentRepository(),
+		Users:    NewMemoryUserRepository(),
+		Holds:    NewMemoryHoldRepository(),
+		Halls:    NewMemoryHallRepository(),
+		Pricing:  NewMemoryPricingRuleRepository(),
+		Promos:   NewMemoryPromoRepository(),
+		Ledger:   NewMemoryLedgerRepository(),
+
+		Reconciliation: NewMemoryReconciliationRepository(),
+		RefreshTokens:  NewMemoryRefreshTokenRepository(),
+		RevokedTokens:  NewMemoryRevokedTokenRepository(),
+		ActionTokens:   NewMemoryActionTokenRepository(),
+		LoginAttempts:  NewMemoryLoginAttemptRepository(),
+		Movies:         NewMemoryMovieRepository(),
+		Cinemas:        NewMemoryCinemaRepository(),
+	}
+}
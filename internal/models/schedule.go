@@ -0,0 +1,274 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"cinema/internal/service"
+)
+
+var (
+	ErrScheduleConflict = errors.New("session overlaps another session in this hall")
+	ErrInvalidSchedule  = errors.New("invalid schedule")
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+type ScheduleTemplate struct {
+	MovieID   int      `json:"movie_id"`
+	HallID    int      `json:"hall_id"`
+	Days      []string `json:"days"`
+	Times     []string `json:"times"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	BasePrice float64  `json:"base_price"`
+	Format    string   `json:"format,omitempty"`
+	DryRun    bool     `json:"dry_run"`
+}
+
+type ScheduleConflict struct {
+	StartTime     time.Time `json:"start_time"`
+	EndTime       time.Time `json:"end_time"`
+	ConflictsWith int       `json:"conflicts_with,omitempty"`
+	Reason        string    `json:"reason"`
+}
+
+type SchedulePlan struct {
+	DryRun    bool               `json:"dry_run"`
+	Sessions  []Session          `json:"sessions"`
+	Conflicts []ScheduleConflict `json:"conflicts"`
+	Created   int                `json:"created"`
+}
+
+func movieDuration(m Movie) time.Duration {
+	if m.Runtime > 0 {
+		return time.Duration(m.Runtime) * time.Minute
+	}
+	return service.DefaultSessionRuntime()
+}
+
+func sessionEnd(repos Repositories, s Session, runtimes map[int]time.Duration) (time.Time, error) {
+	if !s.EndTime.IsZero() {
+		return s.EndTime, nil
+	}
+	d, ok := runtimes[s.MovieID]
+	if !ok {
+		m, found, err := repos.Movies.GetByID(s.MovieID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		d = service.DefaultSessionRuntime()
+		if found {
+			d = movieDuration(m)
+		}
+		runtimes[s.MovieID] = d
+	}
+	return s.StartTime.Add(d), nil
+}
+
+func overlaps(aStart, aEnd, bStart, bEnd time.Time, buffer time.Duration) bool {
+	return aStart.Before(bEnd.Add(buffer)) && bStart.Before(aEnd.Add(buffer))
+}
+
+func hallConflicts(repos Repositories, hallID int, planned []Session) ([]ScheduleConflict, error) {
+	all, err := repos.Sessions.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	buffer := service.SessionCleaningBuffer()
+	runtimes := map[int]time.Duration{}
+
+	var existing []Session
+	for _, s := range all {
+		if s.HallID != hallID || s.Status == SessionCancelled {
+			continue
+		}
+		if s.EndTime, err = sessionEnd(repos, s, runtimes); err != nil {
+			return nil, err
+		}
+		existing = append(existing, s)
+	}
+
+	var conflicts []ScheduleConflict
+planned:
+	for i, p := range planned {
+		loc := p.Location()
+		for _, e := range existing {
+			if e.ID == p.ID {
+				continue
+			}
+			if overlaps(p.StartTime, p.EndTime, e.StartTime, e.EndTime, buffer) {
+				conflicts = append(conflicts, ScheduleConflict{
+					StartTime:     p.StartTime,
+					EndTime:       p.EndTime,
+					ConflictsWith: e.ID,
+					Reason: fmt.Sprintf("overlaps session %d (%s, %s-%s)", e.ID, e.MovieTitle,
+						e.StartTime.In(loc).Format("02.01 15:04"), e.EndTime.In(loc).Format("15:04")),
+				})
+				continue planned
+			}
+		}
+		for _, q := range planned[:i] {
+			if overlaps(p.StartTime, p.EndTime, q.StartTime, q.EndTime, buffer) {
+				conflicts = append(conflicts, ScheduleConflict{
+					StartTime: p.StartTime,
+					EndTime:   p.EndTime,
+					Reason:    "overlaps another generated session at " + q.StartTime.In(loc).Format("02.01 15:04"),
+				})
+				break
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+func PlanSchedule(repos Repositories, t ScheduleTemplate) (SchedulePlan, error) {
+	plan := SchedulePlan{DryRun: t.DryRun, Sessions: []Session{}, Conflicts: []ScheduleConflict{}}
+	if t.MovieID == 0 || t.HallID == 0 {
+		return plan, fmt.Errorf("%w: movie_id and hall_id are required", ErrInvalidSchedule)
+	}
+	if len(t.Days) == 0 || len(t.Times) == 0 {
+		return plan, fmt.Errorf("%w: days and times are required", ErrInvalidSchedule)
+	}
+	if t.BasePrice <= 0 {
+		return plan, fmt.Errorf("%w: base_price must be positive", ErrInvalidSchedule)
+	}
+
+	hall, ok, err := repos.Halls.GetByID(t.HallID)
+	if err != nil {
+		return plan, err
+	}
+	if !ok {
+		return plan, fmt.Errorf("%w: hall not found", ErrInvalidSchedule)
+	}
+	cinema, ok, err := repos.Cinemas.GetByName(hall.CinemaName)
+	if err != nil {
+		return plan, err
+	}
+	if !ok {
+		return plan, ErrCinemaNotFound
+	}
+
+	loc := service.LoadLocation(cinema.Timezone)
+	from, err := time.ParseInLocation("2006-01-02", t.From, loc)
+	if err != nil {
+		return plan, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidSchedule)
+	}
+	to, err := time.ParseInLocation("2006-01-02", t.To, loc)
+	if err != nil {
+		return plan, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidSchedule)
+	}
+	if to.Before(from) {
+		return plan, fmt.Errorf("%w: to is before from", ErrInvalidSchedule)
+	}
+	if to.Sub(from) > service.MaxScheduleDays*24*time.Hour {
+		return plan, fmt.Errorf("%w: date range is limited to %d days", ErrInvalidSchedule, service.MaxScheduleDays)
+	}
+
+	days := map[time.Weekday]bool{}
+	for _, d := range t.Days {
+		name := strings.ToLower(strings.TrimSpace(d))
+		if len(name) > 3 {
+			name = name[:3]
+		}
+		wd, ok := weekdays[name]
+		if !ok {
+			return plan, fmt.Errorf("%w: unknown day %q", ErrInvalidSchedule, d)
+		}
+		days[wd] = true
+	}
+	type clock struct{ hour, minute int }
+	var times []clock
+	for _, s := range t.Times {
+		tm, err := time.Parse("15:04", strings.TrimSpace(s))
+		if err != nil {
+			return plan, fmt.Errorf("%w: time %q must be HH:MM", ErrInvalidSchedule, s)
+		}
+		times = append(times, clock{tm.Hour(), tm.Minute()})
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].hour*60+times[i].minute < times[j].hour*60+times[j].minute
+	})
+
+	movie, ok, err := repos.Movies.GetByID(t.MovieID)
+	if err != nil {
+		return plan, err
+	}
+	if !ok {
+		return plan, ErrMovieNotFound
+	}
+
+	now := time.Now()
+	runtime := movieDuration(movie)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if !days[day.Weekday()] {
+			continue
+		}
+		for _, c := range times {
+			start := time.Date(day.Year(), day.Month(), day.Day(), c.hour, c.minute, 0, 0, loc)
+			if !start.After(now) {
+				continue
+			}
+			plan.Sessions = append(plan.Sessions, Session{
+				MovieID:    movie.ID,
+				MovieTitle: movie.Title,
+				BasePrice:  t.BasePrice,
+				Format:     t.Format,
+				MinAge:     movie.MinAge,
+				CinemaName: hall.CinemaName,
+				City:       cinema.City,
+				Timezone:   cinema.Timezone,
+				HallID:     hall.ID,
+				Hall:       hall.Name,
+				StartTime:  start,
+				EndTime:    start.Add(runtime),
+				Status:     SessionScheduled,
+			})
+			if len(plan.Sessions) > service.MaxScheduleSessions {
+				return plan, fmt.Errorf("%w: schedule generates more than %d sessions", ErrInvalidSchedule, service.MaxScheduleSessions)
+			}
+		}
+	}
+	if len(plan.Sessions) == 0 {
+		return plan, fmt.Errorf("%w: no upcoming sessions match the template", ErrInvalidSchedule)
+	}
+
+	conflicts, err := hallConflicts(repos, hall.ID, plan.Sessions)
+	if err != nil {
+		return plan, err
+	}
+	if conflicts != nil {
+		plan.Conflicts = conflicts
+	}
+	return plan, nil
+}
+
+func ApplySchedule(repos Repositories, t ScheduleTemplate) (SchedulePlan, error) {
+	plan, err := PlanSchedule(repos, t)
+	if err != nil || t.DryRun {
+		return plan, err
+	}
+	if len(plan.Conflicts) > 0 {
+		return plan, ErrScheduleConflict
+	}
+	hall, _, err := repos.Halls.GetByID(t.HallID)
+	if err != nil {
+		return plan, err
+	}
+	for i, s := range plan.Sessions {
+		s.AvailableSeats = hall.SeatCodes()
+		created, err := repos.Sessions.Add(s)
+		if err != nil {
+			return plan, err
+		}
+		plan.Sessions[i] = created
+		plan.Created++
+	}
+	return plan, nil
+}
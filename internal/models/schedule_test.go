@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newScheduleFixture stores a cinema with one small hall and a movie, the
+// minimum CreateSession and PlanSchedule need.
+func newScheduleFixture(t *testing.T) (Repositories, Hall, Movie) {
+	t.Helper()
+	repos := NewMemoryRepositories()
+	if _, err := repos.Cinemas.Create(Cinema{Name: "Test Cinema", City: "Almaty", Address: "Test St 1", Timezone: "Asia/Almaty"}); err != nil {
+		t.Fatalf("create cinema: %v", err)
+	}
+	hall, err := repos.Halls.Add(Hall{
+		CinemaName:    "Test Cinema",
+		Name:          "Hall 1",
+		Rows:          2,
+		Columns:       4,
+		RowCategories: map[string]SeatCategory{"B": SeatVIP},
+	})
+	if err != nil {
+		t.Fatalf("add hall: %v", err)
+	}
+	movie, err := repos.Movies.Upsert(Movie{ID: 1, Title: "Fixture", Runtime: 90})
+	if err != nil {
+		t.Fatalf("add movie: %v", err)
+	}
+	return repos, hall, movie
+}
+
+func TestCreateSessionValidatesStartAndPrice(t *testing.T) {
+	repos, hall, movie := newScheduleFixture(t)
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name  string
+		start time.Time
+		price float64
+	}{
+		{"zero start", time.Time{}, 2000},
+		{"past start", time.Now().Add(-time.Hour), 2000},
+		{"zero price", tomorrow, 0},
+		{"negative price", tomorrow, -100},
+	}
+	for _, tt := range tests {
+		_, err := CreateSession(repos, Session{MovieID: movie.ID, HallID: hall.ID, StartTime: tt.start, BasePrice: tt.price})
+		if err == nil {
+			t.Errorf("%s: CreateSession succeeded", tt.name)
+		}
+	}
+
+	if _, err := CreateSession(repos, Session{MovieID: movie.ID, HallID: hall.ID, StartTime: tomorrow, BasePrice: 2000}); err != nil {
+		t.Fatalf("valid session: %v", err)
+	}
+}
+
+func TestPlanScheduleRejectsNonPositivePrice(t *testing.T) {
+	repos, hall, movie := newScheduleFixture(t)
+	from := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	for _, price := range []float64{0, -500} {
+		_, err := PlanSchedule(repos, ScheduleTemplate{
+			MovieID:   movie.ID,
+			HallID:    hall.ID,
+			Days:      []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"},
+			Times:     []string{"18:00"},
+			From:      from,
+			To:        from,
+			BasePrice: price,
+		})
+		if !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("base_price %v: err = %v, want ErrInvalidSchedule", price, err)
+		}
+	}
+}
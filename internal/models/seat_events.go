@@ -0,0 +1,40 @@
+package models
+
+import (
+	"sync/atomic"
+
+	"cinema/internal/service"
+)
+
+// Set while a Mongo change stream feeds the bus. The stream then reports every
+// hold change, including those made by other instances, so publishing locally
+// as well would deliver each event twice.
+var holdStreamActive atomic.Bool
+
+func holdEventType(s HoldStatus) service.SeatEventType {
+	switch s {
+	case HoldSold:
+		return service.SeatSold
+	case HoldReleased:
+		return service.SeatReleased
+	default:
+		return service.SeatHeld
+	}
+}
+
+// publishSeats reports seat changes that came from a hold document. Those are
+// left to the change stream when it is running.
+func publishSeats(t service.SeatEventType, sessionID int, seats ...string) {
+	if holdStreamActive.Load() {
+		return
+	}
+	publishSessionSeats(t, sessionID, seats...)
+}
+
+// publishSessionSeats reports seat changes made on the session alone, which
+// the hold stream never sees.
+func publishSessionSeats(t service.SeatEventType, sessionID int, seats ...string) {
+	for _, seat := range seats {
+		service.SeatEvents().Publish(service.SeatEvent{Type: t, SessionID: sessionID, Seat: seat})
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// withHoldStream marks the change stream as running for the test, so only
+// events that bypass hold documents reach the bus.
+func withHoldStream(t *testing.T) {
+	t.Helper()
+	holdStreamActive.Store(true)
+	t.Cleanup(func() { holdStreamActive.Store(false) })
+}
+
+func releasedSeats(events <-chan service.SeatEvent) []string {
+	var seats []string
+	for {
+		select {
+		case e := <-events:
+			if e.Type == service.SeatReleased {
+				seats = append(seats, e.Seat)
+			}
+		default:
+			slices.Sort(seats)
+			return seats
+		}
+	}
+}
+
+func TestLegacySeatReturnIsPublishedWhileStreamRuns(t *testing.T) {
+	repos := NewMemoryRepositories()
+	s := newHoldTestSession(t, repos)
+	if _, err := repos.Sessions.ReserveSeats(s.ID, []string{"A1"}); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	withHoldStream(t)
+	events, cancel := service.SeatEvents().Subscribe(s.ID)
+	defer cancel()
+
+	if err := returnSessionSeats(repos, primitive.NewObjectID(), s.ID, []string{"A1"}); err != nil {
+		t.Fatalf("return seats: %v", err)
+	}
+	if got := releasedSeats(events); !slices.Equal(got, []string{"A1"}) {
+		t.Fatalf("released events = %v, want [A1]", got)
+	}
+}
+
+func TestHallChangePublishesFreedSeatsWhileStreamRuns(t *testing.T) {
+	repos, hall, movie := newScheduleFixture(t)
+	bigger, err := repos.Halls.Add(Hall{CinemaName: "Test Cinema", Name: "Hall 2", Rows: 3, Columns: 4})
+	if err != nil {
+		t.Fatalf("add hall: %v", err)
+	}
+	s, err := CreateSession(repos, Session{MovieID: movie.ID, HallID: hall.ID, StartTime: time.Now().Add(24 * time.Hour), BasePrice: 2000})
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	withHoldStream(t)
+	events, cancel := service.SeatEvents().Subscribe(s.ID)
+	defer cancel()
+
+	if _, err := UpdateSession(repos, s.ID, SessionUpdate{HallID: &bigger.ID}); err != nil {
+		t.Fatalf("UpdateSession: %v", err)
+	}
+	want := []string{"C1", "C2", "C3", "C4"}
+	if got := releasedSeats(events); !slices.Equal(got, want) {
+		t.Fatalf("released events = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,416 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SessionStatus string
+
+const (
+	SessionScheduled SessionStatus = "scheduled"
+	SessionCancelled SessionStatus = "cancelled"
+)
+
+var (
+	ErrSessionNotFound  = errors.New("session not found")
+	ErrSessionCancelled = errors.New("session is cancelled")
+	ErrSessionStarted   = errors.New("session has already started")
+	ErrSessionChanged   = errors.New("session changed while updating, try again")
+	ErrNotExchangeable  = errors.New("order is not eligible for exchange")
+	ErrSeatsNotReturned = errors.New("order moved but its old seats were not returned")
+)
+
+type SessionUpdate struct {
+	StartTime *time.Time `json:"start_time"`
+	BasePrice *float64   `json:"base_price"`
+	HallID    *int       `json:"hall_id"`
+}
+
+type SessionImpact struct {
+	Session        Session  `json:"session"`
+	AffectedOrders int      `json:"affected_orders"`
+	Notified       int      `json:"notified"`
+	Refunded       int      `json:"refunded,omitempty"`
+	RefundedAmount float64  `json:"refunded_amount,omitempty"`
+	FailedOrders   []string `json:"failed_orders,omitempty"`
+}
+
+func activeSession(repos Repositories, id int) (Session, error) {
+	s, ok, err := repos.Sessions.GetByID(id)
+	if err != nil {
+		return Session{}, err
+	}
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	if s.Status == SessionCancelled {
+		return s, ErrSessionCancelled
+	}
+	if !s.StartTime.After(time.Now()) {
+		return s, ErrSessionStarted
+	}
+	return s, nil
+}
+
+func hallSeatCodes(repos Repositories, hallID int) ([]string, Hall, error) {
+	if hallID == 0 {
+		return defaultSeats, Hall{}, nil
+	}
+	h, ok, err := repos.Halls.GetByID(hallID)
+	if err != nil {
+		return nil, Hall{}, err
+	}
+	if !ok {
+		return nil, Hall{}, errors.New("hall not found")
+	}
+	return h.SeatCodes(), h, nil
+}
+
+func UpdateSession(repos Repositories, id int, u SessionUpdate) (SessionImpact, error) {
+	current, err := activeSession(repos, id)
+	if err != nil {
+		return SessionImpact{}, err
+	}
+	updated := current
+
+	if u.BasePrice != nil {
+		if *u.BasePrice <= 0 {
+			return SessionImpact{}, errors.New("base_price must be positive")
+		}
+		updated.BasePrice = *u.BasePrice
+	}
+	if u.StartTime != nil {
+		if !u.StartTime.After(time.Now()) {
+			return SessionImpact{}, errors.New("start_time must be in the future")
+		}
+		updated.StartTime = *u.StartTime
+		runtime := service.DefaultSessionRuntime()
+		if m, ok, err := repos.Movies.GetByID(current.MovieID); err != nil {
+			return SessionImpact{}, err
+		} else if ok {
+			runtime = movieDuration(m)
+		}
+		updated.EndTime = updated.StartTime.Add(runtime)
+	}
+	if u.HallID != nil && *u.HallID != current.HallID {
+		oldCodes, _, err := hallSeatCodes(repos, current.HallID)
+		if err != nil {
+			return SessionImpact{}, err
+		}
+		newCodes, h, err := hallSeatCodes(repos, *u.HallID)
+		if err != nil {
+			return SessionImpact{}, err
+		}
+		if h.CinemaName != current.CinemaName {
+			return SessionImpact{}, errors.New("hall does not belong to this cinema")
+		}
+
+		free := make(map[string]bool, len(current.AvailableSeats))
+		for _, seat := range current.AvailableSeats {
+			free[seat] = true
+		}
+		inNewHall := make(map[string]bool, len(newCodes))
+		for _, seat := range newCodes {
+			inNewHall[seat] = true
+		}
+		taken := map[string]bool{}
+		var missing []string
+		for _, seat := range oldCodes {
+			if free[seat] {
+				continue
+			}
+			taken[seat] = true
+			if !inNewHall[seat] {
+				missing = append(missing, seat)
+			}
+		}
+		if len(missing) > 0 {
+			return SessionImpact{}, fmt.Errorf("sold seats %v do not exist in hall %s", missing, h.Name)
+		}
+
+		updated.HallID = h.ID
+		updated.Hall = h.Name
+		updated.TotalSeats = len(newCodes)
+		updated.AvailableSeats = make([]string, 0, len(newCodes))
+		for _, seat := range newCodes {
+			if !taken[seat] {
+				updated.AvailableSeats = append(updated.AvailableSeats, seat)
+			}
+		}
+	}
+
+	moved := !updated.StartTime.Equal(current.StartTime) || updated.HallID != current.HallID
+	if moved && updated.HallID != 0 {
+		if updated.EndTime.IsZero() {
+			runtimes := map[int]time.Duration{}
+			if updated.EndTime, err = sessionEnd(repos, updated, runtimes); err != nil {
+				return SessionImpact{}, err
+			}
+		}
+		conflicts, err := hallConflicts(repos, updated.HallID, []Session{updated})
+		if err != nil {
+			return SessionImpact{}, err
+		}
+		if len(conflicts) > 0 {
+			return SessionImpact{}, fmt.Errorf("%w: %s", ErrScheduleConflict, conflicts[0].Reason)
+		}
+	}
+
+	ok, err := repos.Sessions.Update(current, updated)
+	if err != nil {
+		return SessionImpact{}, err
+	}
+	if !ok {
+		return SessionImpact{}, ErrSessionChanged
+	}
+
+	if updated.HallID != current.HallID {
+		wasFree := make(map[string]bool, len(current.AvailableSeats))
+		for _, seat := range current.AvailableSeats {
+			wasFree[seat] = true
+		}
+		var freed []string
+		for _, seat := range updated.AvailableSeats {
+			if !wasFree[seat] {
+				freed = append(freed, seat)
+			}
+		}
+		publishSessionSeats(service.SeatReleased, updated.ID, freed...)
+	}
+
+	impact := SessionImpact{Session: updated}
+	if !moved {
+		return impact, nil
+	}
+	if impact.AffectedOrders, err = repos.Orders.UpdateSessionDetails(updated.ID, updated.CinemaName, updated.Hall, updated.StartTime, time.Now()); err != nil {
+		return impact, err
+	}
+	orders, err := repos.Orders.ListBySession(updated.ID)
+	if err != nil {
+		return impact, err
+	}
+	notified := map[string]bool{}
+	for _, o := range orders {
+		if o.PaymentStatus != "paid" || notified[o.CustomerEmail] {
+			continue
+		}
+		notified[o.CustomerEmail] = true
+		service.SendSessionChangeNotification(o.CustomerEmail, updated.MovieTitle, current.LocalStart(), updated.LocalStart(), updated.CinemaName, updated.Hall)
+	}
+	impact.Notified = len(notified)
+	return impact, nil
+}
+
+func CancelSession(repos Repositories, id int, reason string) (SessionImpact, error) {
+	s, err := activeSession(repos, id)
+	if err != nil {
+		return SessionImpact{}, err
+	}
+	now := time.Now()
+	ok, err := repos.Sessions.Cancel(id, reason, now)
+	if err != nil {
+		return SessionImpact{}, err
+	}
+	if !ok {
+		return SessionImpact{}, ErrSessionCancelled
+	}
+	s.Status = SessionCancelled
+	s.CancelledAt = now
+	s.CancelReason = reason
+
+	impact := SessionImpact{Session: s}
+	orders, err := repos.Orders.ListBySession(id)
+	if err != nil {
+		return impact, err
+	}
+	for _, o := range orders {
+		if o.PaymentStatus != "paid" && o.PaymentStatus != "reserved" {
+			continue
+		}
+		impact.AffectedOrders++
+		cancelled, amount, err := CancelOrder(repos, o, "Session cancelled")
+		if err != nil {
+			log.Printf("[SESSIONS] refund of order %s for cancelled session %d failed: %v", o.ID.Hex(), id, err)
+			impact.FailedOrders = append(impact.FailedOrders, o.ID.Hex())
+			continue
+		}
+		if cancelled.PaymentStatus == "refunded" {
+			impact.Refunded++
+			impact.RefundedAmount += amount
+		}
+		service.SendSessionCancelledNotification(o.CustomerEmail, s.MovieTitle, s.LocalStart(), reason, amount)
+		impact.Notified++
+	}
+	return impact, nil
+}
+
+func orderSeats(o Order) []string {
+	var seats []string
+	for _, it := range o.Items {
+		seats = append(seats, it.Seat)
+	}
+	if len(seats) == 0 && o.Seat != "" {
+		seats = strings.Split(o.Seat, ", ")
+	}
+	return seats
+}
+
+func ExchangeOrder(repos Repositories, o Order, targetID int, seats []string) (Order, error) {
+	if o.PaymentStatus != "paid" || o.SessionChangedAt.IsZero() || o.SessionID == targetID {
+		return o, ErrNotExchangeable
+	}
+	current, ok, err := repos.Sessions.GetByID(o.SessionID)
+	if err != nil {
+		return o, err
+	}
+	if !ok {
+		return o, ErrSessionNotFound
+	}
+	target, err := activeSession(repos, targetID)
+	if err != nil {
+		return o, err
+	}
+	if target.MovieID != current.MovieID {
+		return o, fmt.Errorf("%w: target session shows a different movie", ErrNotExchangeable)
+	}
+
+	oldSeats := orderSeats(o)
+	if len(oldSeats) == 0 {
+		return o, ErrNotExchangeable
+	}
+	if len(seats) == 0 {
+		seats = oldSeats
+	}
+	if len(seats) != len(oldSeats) {
+		return o, fmt.Errorf("%w: exactly %d seats are required", ErrNotExchangeable, len(oldSeats))
+	}
+	// The order keeps the price it was paid at, so every new seat must be in
+	// the same category as the seat it replaces.
+	_, fromHall, err := hallSeatCodes(repos, current.HallID)
+	if err != nil {
+		return o, err
+	}
+	_, toHall, err := hallSeatCodes(repos, target.HallID)
+	if err != nil {
+		return o, err
+	}
+	for i, seat := range seats {
+		was := fromHall.Category(oldSeats[i])
+		if i < len(o.Items) && o.Items[i].Category != "" {
+			was = SeatCategory(o.Items[i].Category)
+		}
+		if now := toHall.Category(seat); now != was {
+			return o, fmt.Errorf("%w: seat %s is %s but %s was %s", ErrNotExchangeable, seat, now, oldSeats[i], was)
+		}
+	}
+	if _, err := repos.Sessions.ReserveSeats(target.ID, seats); err != nil {
+		return o, err
+	}
+	var created []SeatHold
+	undo := func() {
+		for _, h := range created {
+			if _, err := repos.Holds.Transition(h.ID, HoldSold, HoldReleased); err != nil {
+				log.Printf("[HOLDS] undo hold %s: %v", h.ID.Hex(), err)
+			}
+		}
+		for _, seat := range seats {
+			if err := repos.Sessions.ReleaseSeat(target.ID, seat); err != nil {
+				log.Printf("[HOLDS] undo seat %s in session %d: %v", seat, target.ID, err)
+			}
+		}
+	}
+	owner := o.UserEmail
+	if owner == "" {
+		owner = o.CustomerEmail
+	}
+	for _, seat := range seats {
+		h, err := repos.Holds.Create(SeatHold{
+			SessionID: target.ID,
+			Seat:      seat,
+			Owner:     owner,
+			OrderID:   o.ID,
+			Status:    HoldSold,
+			ExpiresAt: target.StartTime,
+		})
+		if err != nil {
+			undo()
+			return o, err
+		}
+		created = append(created, h)
+	}
+
+	moved := o
+	moved.SessionID = target.ID
+	moved.CinemaName = target.CinemaName
+	moved.Hall = target.Hall
+	moved.StartTime = target.StartTime
+	moved.Timezone = target.Timezone
+	moved.SessionChangedAt = time.Time{}
+	moved.Items = append([]OrderItem(nil), o.Items...)
+	for i := range moved.Items {
+		moved.Items[i].Seat = seats[i]
+	}
+	moved.Seat = strings.Join(seats, ", ")
+	ok, err = repos.Orders.MoveToSession(moved)
+	if err == nil && !ok {
+		err = ErrNotExchangeable
+	}
+	if err != nil {
+		undo()
+		return o, err
+	}
+	publishSeats(service.SeatSold, target.ID, seats...)
+
+	if err := returnSessionSeats(repos, o.ID, current.ID, oldSeats); err != nil {
+		return moved, fmt.Errorf("%w: %v", ErrSeatsNotReturned, err)
+	}
+	return moved, nil
+}
+
+// Seats sold without holds (orders placed before holds existed) are released
+// directly on the session.
+func returnSessionSeats(repos Repositories, orderID primitive.ObjectID, sessionID int, seats []string) error {
+	holds, err := repos.Holds.GetByOrder(orderID)
+	if err != nil {
+		return err
+	}
+	handled := map[string]bool{}
+	for _, h := range holds {
+		if h.SessionID != sessionID {
+			continue
+		}
+		handled[h.Seat] = true
+		if h.Status == HoldReleased {
+			continue
+		}
+		ok, err := repos.Holds.Transition(h.ID, h.Status, HoldReleased)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := repos.Sessions.ReleaseSeat(sessionID, h.Seat); err != nil {
+			return err
+		}
+		publishSeats(service.SeatReleased, sessionID, h.Seat)
+	}
+	for _, seat := range seats {
+		if handled[seat] {
+			continue
+		}
+		if err := repos.Sessions.ReleaseSeat(sessionID, seat); err != nil {
+			return err
+		}
+		publishSessionSeats(service.SeatReleased, sessionID, seat)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExchangeOrderKeepsSeatCategory(t *testing.T) {
+	repos, hall, movie := newScheduleFixture(t)
+	add := func(start time.Time) Session {
+		t.Helper()
+		s, err := CreateSession(repos, Session{MovieID: movie.ID, HallID: hall.ID, StartTime: start, BasePrice: 2000})
+		if err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+		return s
+	}
+	from := add(time.Now().Add(24 * time.Hour))
+	to := add(time.Now().Add(48 * time.Hour))
+	if _, err := repos.Sessions.ReserveSeats(from.ID, []string{"A1"}); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	order, err := repos.Orders.Save(Order{
+		ID:               primitive.NewObjectID(),
+		UserEmail:        "buyer@example.com",
+		SessionID:        from.ID,
+		Seat:             "A1",
+		Items:            []OrderItem{{Seat: "A1", Category: string(SeatStandard), Price: 2000}},
+		FinalPrice:       2000,
+		PaymentStatus:    "paid",
+		SessionChangedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("save order: %v", err)
+	}
+
+	if _, err := ExchangeOrder(repos, order, to.ID, []string{"B1"}); !errors.Is(err, ErrNotExchangeable) {
+		t.Fatalf("exchange into vip: err = %v, want ErrNotExchangeable", err)
+	}
+	assertAvailable(t, repos, to.ID, "A1", "A2", "A3", "A4", "B1", "B2", "B3", "B4")
+
+	moved, err := ExchangeOrder(repos, order, to.ID, []string{"A2"})
+	if err != nil {
+		t.Fatalf("exchange into standard: %v", err)
+	}
+	if moved.Items[0].Seat != "A2" || moved.Items[0].Category != string(SeatStandard) {
+		t.Fatalf("items = %+v, want standard A2", moved.Items)
+	}
+}
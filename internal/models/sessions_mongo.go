@@ -3,7 +3,6 @@ package models
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"cinema/internal/service"
@@ -12,7 +11,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func AddSessionMongo(s Session) (Session, error) {
+var defaultSeats = []string{"A1", "A2", "A3", "B1", "B2", "B3", "C1", "C2", "C3"}
+
+type mongoSessionRepository struct{}
+
+func NewMongoSessionRepository() SessionRepository {
+	return &mongoSessionRepository{}
+}
+
+func (r *mongoSessionRepository) Add(s Session) (Session, error) {
 	id, err := nextID("sessions")
 	if err != nil {
 		return Session{}, err
@@ -22,8 +29,8 @@ func AddSessionMongo(s Session) (Session, error) {
 	if len(s.AvailableSeats) > 0 {
 		s.TotalSeats = len(s.AvailableSeats)
 	} else {
-		s.AvailableSeats = []string{"A1", "A2", "A3", "B1", "B2", "B3", "C1", "C2", "C3"}
-		s.TotalSeats = 9
+		s.AvailableSeats = append([]string(nil), defaultSeats...)
+		s.TotalSeats = len(defaultSeats)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -36,13 +43,11 @@ func AddSessionMongo(s Session) (Session, error) {
 	return s, nil
 }
 
-func GetAllSessionsMongo() ([]Session, error) {
+func (r *mongoSessionRepository) GetAll() ([]Session, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	coll := service.MongoDB.Collection("sessions")
-
-	cur, err := coll.Find(ctx, bson.M{})
+	cur, err := service.SessionsCollection().Find(ctx, bson.M{})
 	if err != nil {
 		return nil, err
 	}
@@ -55,7 +60,7 @@ func GetAllSessionsMongo() ([]Session, error) {
 	return sessions, nil
 }
 
-func GetSessionByIDMongo(id int) (Session, bool, error) {
+func (r *mongoSessionRepository) GetByID(id int) (Session, bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -70,35 +75,33 @@ func GetSessionByIDMongo(id int) (Session, bool, error) {
 	return s, true, nil
 }
 
-func FilterSessionsMongo(cinema string, date string, maxPrice float64, onlyWithSeats bool) ([]Session, error) {
-	if service.MongoDB == nil {
-		return FilterSessions(maxPrice, onlyWithSeats), nil
-	}
-
+func (r *mongoSessionRepository) Filter(f SessionFilter) ([]Session, error) {
 	filter := bson.M{}
 
-	if cinema != "" {
-		filter["cinema_name"] = cinema
+	if len(f.Cinemas) > 0 {
+		filter["cinema_name"] = bson.M{"$in": f.Cinemas}
 	}
 
-	if date != "" && date != "all" {
-		loc, _ := time.LoadLocation("Asia/Almaty")
-		dayStart, err := time.ParseInLocation("2006-01-02", date, loc)
+	if f.byDate() {
+		dayStart, dayEnd, err := sessionDayWindow(f.Date)
 		if err != nil {
-			return nil, fmt.Errorf("invalid date format: %v", err)
+			return nil, err
 		}
-		dayEnd := dayStart.Add(24 * time.Hour)
 		filter["start_time"] = bson.M{"$gte": dayStart, "$lt": dayEnd}
 	}
 
-	if maxPrice > 0 {
-		filter["base_price"] = bson.M{"$lte": maxPrice}
+	if f.MaxPrice > 0 {
+		filter["base_price"] = bson.M{"$lte": f.MaxPrice}
 	}
 
-	if onlyWithSeats {
+	if f.OnlyWithSeats {
 		filter["available_seats.0"] = bson.M{"$exists": true}
 	}
 
+	if !f.IncludeCancelled {
+		filter["status"] = bson.M{"$ne": SessionCancelled}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
@@ -114,18 +117,21 @@ func FilterSessionsMongo(cinema string, date string, maxPrice float64, onlyWithS
 		if err := cur.Decode(&s); err != nil {
 			return nil, err
 		}
+		if f.byDate() && s.LocalDate() != f.Date {
+			continue
+		}
 		out = append(out, s)
 	}
 
 	return out, cur.Err()
 }
 
-func ReserveSeatMongo(sessionID int, seat string) (Session, error) {
+func (r *mongoSessionRepository) ReserveSeats(sessionID int, seats []string) (Session, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{"id": sessionID, "available_seats": seat}
-	update := bson.M{"$pull": bson.M{"available_seats": seat}}
+	filter := bson.M{"id": sessionID, "status": bson.M{"$ne": SessionCancelled}, "available_seats": bson.M{"$all": seats}}
+	update := bson.M{"$pull": bson.M{"available_seats": bson.M{"$in": seats}}}
 
 	res, err := service.SessionsCollection().UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -135,7 +141,7 @@ func ReserveSeatMongo(sessionID int, seat string) (Session, error) {
 		return Session{}, errors.New("seat not available")
 	}
 
-	updated, ok, err := GetSessionByIDMongo(sessionID)
+	updated, ok, err := r.GetByID(sessionID)
 	if err != nil {
 		return Session{}, err
 	}
@@ -145,18 +151,56 @@ func ReserveSeatMongo(sessionID int, seat string) (Session, error) {
 	return updated, nil
 }
 
-func DeleteSessionMongo(id int) error {
+func (r *mongoSessionRepository) ReleaseSeat(sessionID int, seat string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	res, err := service.SessionsCollection().DeleteOne(ctx, bson.M{"id": id})
+	res, err := service.SessionsCollection().UpdateOne(ctx,
+		bson.M{"id": sessionID},
+		bson.M{"$addToSet": bson.M{"available_seats": seat}},
+	)
 	if err != nil {
 		return err
 	}
-
-	if res.DeletedCount == 0 {
+	if res.MatchedCount == 0 {
 		return errors.New("session not found")
 	}
-
 	return nil
 }
+
+func (r *mongoSessionRepository) Update(current Session, updated Session) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.SessionsCollection().UpdateOne(ctx,
+		bson.M{"id": current.ID, "status": bson.M{"$ne": SessionCancelled}, "available_seats": current.AvailableSeats},
+		bson.M{"$set": bson.M{
+			"start_time":      updated.StartTime,
+			"end_time":        updated.EndTime,
+			"base_price":      updated.BasePrice,
+			"cinema_name":     updated.CinemaName,
+			"hall_id":         updated.HallID,
+			"hall":            updated.Hall,
+			"available_seats": updated.AvailableSeats,
+			"total_seats":     updated.TotalSeats,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (r *mongoSessionRepository) Cancel(id int, reason string, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.SessionsCollection().UpdateOne(ctx,
+		bson.M{"id": id, "status": bson.M{"$ne": SessionCancelled}},
+		bson.M{"$set": bson.M{"status": SessionCancelled, "cancelled_at": at, "cancel_reason": reason}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
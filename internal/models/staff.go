@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"cinema/internal/service"
+)
+
+var (
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrCinemaScope      = errors.New("staff roles require at least one cinema")
+	ErrAlreadyCheckedIn = errors.New("order already checked in")
+	ErrNotCheckable     = errors.New("only paid orders can be checked in")
+)
+
+func AssignRole(repos Repositories, email string, role string, cinemas []string) (User, error) {
+	role = service.NormalizeRole(role)
+	if !service.IsValidRole(role) {
+		return User{}, ErrInvalidRole
+	}
+	if !service.IsScopedRole(role) {
+		cinemas = nil
+	} else {
+		if len(cinemas) == 0 {
+			return User{}, ErrCinemaScope
+		}
+		seen := map[string]bool{}
+		scoped := make([]string, 0, len(cinemas))
+		for _, c := range cinemas {
+			exists, err := CinemaExists(repos, c)
+			if err != nil {
+				return User{}, err
+			}
+			if !exists {
+				return User{}, fmt.Errorf("unknown cinema %q", c)
+			}
+			if !seen[c] {
+				seen[c] = true
+				scoped = append(scoped, c)
+			}
+		}
+		cinemas = scoped
+	}
+
+	u, ok, err := repos.Users.GetByEmail(email)
+	if err != nil {
+		return User{}, err
+	}
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	if err := repos.Users.SetRole(u.Email, role, cinemas); err != nil {
+		return User{}, err
+	}
+	if _, err := repos.Users.BumpTokenVersion(u.Email); err != nil {
+		return User{}, err
+	}
+	u.Role = role
+	u.Cinemas = cinemas
+	return u, nil
+}
+
+func CheckInOrder(repos Repositories, o Order, by string) (Order, error) {
+	if !o.CheckedInAt.IsZero() {
+		return o, ErrAlreadyCheckedIn
+	}
+	if o.PaymentStatus != "paid" {
+		return o, ErrNotCheckable
+	}
+	now := time.Now()
+	ok, err := repos.Orders.CheckIn(o.ID, by, now)
+	if err != nil {
+		return o, err
+	}
+	if !ok {
+		return o, ErrAlreadyCheckedIn
+	}
+	o.CheckedInAt = now
+	o.CheckedInBy = by
+	return o, nil
+}
@@ -2,148 +2,1405 @@ package models
 
 import (
 	"errors"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-var (
-	mu sync.RWMutex
+type memorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions []Session
+	nextID   int
+}
+
+func NewMemorySessionRepository() SessionRepository {
+	return &memorySessionRepository{nextID: 1}
+}
 
-	movies   = make([]Movie, 0)
-	sessions = make([]Session, 0)
-	orders   = make([]Order, 0)
+func cloneSession(s Session) Session {
+	s.AvailableSeats = append([]string(nil), s.AvailableSeats...)
+	return s
+}
 
-	nextOrderID   = 1
-	nextSessionID = 1
-)
+func (r *memorySessionRepository) Add(s Session) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-func AddMovie(m Movie) Movie {
-	mu.Lock()
-	defer mu.Unlock()
+	s.ID = r.nextID
+	r.nextID++
 
-	if m.ID == 0 {
-		m.ID = len(movies) + 1
+	if len(s.AvailableSeats) > 0 {
+		s.TotalSeats = len(s.AvailableSeats)
+	} else {
+		s.AvailableSeats = append([]string(nil), defaultSeats...)
+		s.TotalSeats = len(defaultSeats)
 	}
 
-	movies = append(movies, m)
-	return m
+	r.sessions = append(r.sessions, cloneSession(s))
+	return s, nil
 }
 
-func GetAllMovies() []Movie {
-	mu.RLock()
-	defer mu.RUnlock()
+func (r *memorySessionRepository) GetAll() ([]Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	out := make([]Movie, len(movies))
-	copy(out, movies)
-	return out
+	out := make([]Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		out = append(out, cloneSession(s))
+	}
+	return out, nil
 }
 
-func AddSession(s Session) Session {
-	mu.Lock()
-	defer mu.Unlock()
+func (r *memorySessionRepository) GetByID(id int) (Session, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	if s.ID == 0 {
-		s.ID = nextSessionID
-		nextSessionID++
+	for _, s := range r.sessions {
+		if s.ID == id {
+			return cloneSession(s), true, nil
+		}
 	}
-
-	sessions = append(sessions, s)
-	return s
+	return Session{}, false, nil
 }
 
-func GetSessionByID(id int) (Session, bool) {
-	mu.RLock()
-	defer mu.RUnlock()
+func (r *memorySessionRepository) Filter(f SessionFilter) ([]Session, error) {
+	if f.byDate() {
+		if _, _, err := sessionDayWindow(f.Date); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	for _, s := range sessions {
-		if s.ID == id {
-			return s, true
+	out := make([]Session, 0)
+	for _, s := range r.sessions {
+		if f.matches(s) {
+			out = append(out, cloneSession(s))
 		}
 	}
-	return Session{}, false
+	return out, nil
 }
 
-func ReserveSeat(sessionID int, seat string) error {
-	mu.Lock()
-	defer mu.Unlock()
+func (r *memorySessionRepository) ReserveSeats(sessionID int, seats []string) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	for i := range sessions {
-		if sessions[i].ID != sessionID {
+	for i := range r.sessions {
+		if r.sessions[i].ID != sessionID {
 			continue
 		}
+		if r.sessions[i].Status == SessionCancelled {
+			return Session{}, ErrSessionCancelled
+		}
 
-		seats := sessions[i].AvailableSeats
-		pos := -1
-		for j := range seats {
-			if seats[j] == seat {
-				pos = j
-				break
+		available := make(map[string]bool, len(r.sessions[i].AvailableSeats))
+		for _, seat := range r.sessions[i].AvailableSeats {
+			available[seat] = true
+		}
+		for _, seat := range seats {
+			if !available[seat] {
+				return Session{}, errors.New("seat not available")
 			}
+			delete(available, seat)
 		}
 
-		if pos == -1 {
-			return errors.New("seat not available")
+		remaining := make([]string, 0, len(available))
+		for _, seat := range r.sessions[i].AvailableSeats {
+			if available[seat] {
+				remaining = append(remaining, seat)
+			}
 		}
+		r.sessions[i].AvailableSeats = remaining
+		return cloneSession(r.sessions[i]), nil
+	}
 
-		sessions[i].AvailableSeats = append(seats[:pos], seats[pos+1:]...)
+	return Session{}, errors.New("session not found")
+}
+
+func (r *memorySessionRepository) ReleaseSeat(sessionID int, seat string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.sessions {
+		if r.sessions[i].ID != sessionID {
+			continue
+		}
+		for _, s := range r.sessions[i].AvailableSeats {
+			if s == seat {
+				return nil
+			}
+		}
+		r.sessions[i].AvailableSeats = append(r.sessions[i].AvailableSeats, seat)
 		return nil
 	}
-
 	return errors.New("session not found")
 }
 
-func SaveOrder(o Order) Order {
-	mu.Lock()
-	defer mu.Unlock()
+func (r *memorySessionRepository) Update(current Session, updated Session) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.sessions {
+		s := &r.sessions[i]
+		if s.ID != current.ID {
+			continue
+		}
+		if s.Status == SessionCancelled || !slices.Equal(s.AvailableSeats, current.AvailableSeats) {
+			return false, nil
+		}
+		s.StartTime = updated.StartTime
+		s.EndTime = updated.EndTime
+		s.BasePrice = updated.BasePrice
+		s.CinemaName = updated.CinemaName
+		s.HallID = updated.HallID
+		s.Hall = updated.Hall
+		s.AvailableSeats = append([]string(nil), updated.AvailableSeats...)
+		s.TotalSeats = updated.TotalSeats
+		return true, nil
+	}
+	return false, nil
+}
 
-	nextOrderID++
+func (r *memorySessionRepository) Cancel(id int, reason string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	orders = append(orders, o)
-	return o
+	for i := range r.sessions {
+		s := &r.sessions[i]
+		if s.ID != id || s.Status == SessionCancelled {
+			continue
+		}
+		s.Status = SessionCancelled
+		s.CancelledAt = at
+		s.CancelReason = reason
+		return true, nil
+	}
+	return false, nil
 }
 
-func GetAllOrders() []Order {
-	mu.RLock()
-	defer mu.RUnlock()
+type memoryOrderRepository struct {
+	mu     sync.RWMutex
+	orders []Order
+}
 
-	out := make([]Order, len(orders))
-	copy(out, orders)
-	return out
+func NewMemoryOrderRepository() OrderRepository {
+	return &memoryOrderRepository{}
+}
+
+func (r *memoryOrderRepository) Save(o Order) (Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if o.ID.IsZero() {
+		o.ID = primitive.NewObjectID()
+	}
+
+	r.orders = append(r.orders, o)
+	return o, nil
 }
 
-func GetOrderCount() int {
-	mu.RLock()
-	defer mu.RUnlock()
-	return len(orders)
+func (r *memoryOrderRepository) GetAll() ([]Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Order, len(r.orders))
+	copy(out, r.orders)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].ID.Hex() > out[j].ID.Hex()
+	})
+	return out, nil
 }
 
-func GetMovieCount() int {
-	mu.RLock()
-	defer mu.RUnlock()
-	return len(movies)
+func (r *memoryOrderRepository) GetByID(id primitive.ObjectID) (*Order, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, o := range r.orders {
+		if o.ID == id {
+			return &o, true, nil
+		}
+	}
+	return nil, false, nil
 }
 
-func FilterSessions(maxPrice float64, onlyWithSeats bool) []Session {
-	mu.RLock()
-	defer mu.RUnlock()
+func (r *memoryOrderRepository) GetByEmail(email string) ([]Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	out := make([]Session, 0)
-	for _, s := range sessions {
-		if maxPrice > 0 && s.BasePrice > maxPrice {
+	out := make([]Order, 0)
+	for _, o := range r.orders {
+		if o.UserEmail == email || (o.UserEmail == "" && o.CustomerEmail == email) {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryOrderRepository) UpdateStatus(orderID primitive.ObjectID, from string, to string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.orders {
+		if r.orders[i].ID == orderID && r.orders[i].PaymentStatus == from {
+			r.orders[i].PaymentStatus = to
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryOrderRepository) ListBySession(sessionID int) ([]Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Order, 0)
+	for _, o := range r.orders {
+		if o.SessionID == sessionID {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryOrderRepository) UpdateSessionDetails(sessionID int, cinema, hall string, start time.Time, changedAt time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for i := range r.orders {
+		o := &r.orders[i]
+		if o.SessionID != sessionID || (o.PaymentStatus != "paid" && o.PaymentStatus != "reserved") {
 			continue
 		}
-		if onlyWithSeats && len(s.AvailableSeats) == 0 {
+		o.CinemaName = cinema
+		o.Hall = hall
+		o.StartTime = start
+		o.SessionChangedAt = changedAt
+		n++
+	}
+	return n, nil
+}
+
+func (r *memoryOrderRepository) MoveToSession(o Order) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.orders {
+		cur := &r.orders[i]
+		if cur.ID != o.ID || cur.PaymentStatus != "paid" {
 			continue
 		}
-		out = append(out, s)
+		cur.SessionID = o.SessionID
+		cur.CinemaName = o.CinemaName
+		cur.Hall = o.Hall
+		cur.StartTime = o.StartTime
+		cur.Timezone = o.Timezone
+		cur.Seat = o.Seat
+		cur.Items = append([]OrderItem(nil), o.Items...)
+		cur.SessionChangedAt = time.Time{}
+		return true, nil
 	}
-	return out
+	return false, nil
+}
+
+func (r *memoryOrderRepository) CheckIn(orderID primitive.ObjectID, by string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.orders {
+		o := &r.orders[i]
+		if o.ID == orderID && o.PaymentStatus == "paid" && o.CheckedInAt.IsZero() {
+			o.CheckedInAt = at
+			o.CheckedInBy = by
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryPaymentRepository struct {
+	mu       sync.RWMutex
+	payments []Payment
+}
+
+func NewMemoryPaymentRepository() PaymentRepository {
+	return &memoryPaymentRepository{}
+}
+
+func (r *memoryPaymentRepository) Create(p Payment) (*Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.payments {
+		if existing.InvoiceID == p.InvoiceID {
+			return nil, ErrPaymentExists
+		}
+	}
+	if p.ID.IsZero() {
+		p.ID = primitive.NewObjectID()
+	}
+	p.CreatedAt = time.Now()
+	if p.Status == "" {
+		p.Status = PaymentPending
+	}
+
+	r.payments = append(r.payments, p)
+	return &p, nil
+}
+
+func (r *memoryPaymentRepository) GetByInvoice(invoiceID string) (*Payment, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, p := range r.payments {
+		if p.InvoiceID == invoiceID {
+			return &p, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (r *memoryPaymentRepository) ListByOrder(orderID primitive.ObjectID) ([]Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Payment, 0)
+	for i := len(r.payments) - 1; i >= 0; i-- {
+		if r.payments[i].OrderID == orderID {
+			out = append(out, r.payments[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryPaymentRepository) ListByStatus(status PaymentStatus, createdBefore time.Time) ([]Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Payment, 0)
+	for _, p := range r.payments {
+		if p.Status == status && p.CreatedAt.Before(createdBefore) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryPaymentRepository) ListCreatedBetween(from, to time.Time) ([]Payment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Payment, 0)
+	for _, p := range r.payments {
+		if !p.CreatedAt.Before(from) && p.CreatedAt.Before(to) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryPaymentRepository) Transition(invoiceID string, to PaymentStatus, providerRef string, callback any) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.payments {
+		p := &r.payments[i]
+		if p.InvoiceID != invoiceID {
+			continue
+		}
+		if !p.Status.CanTransition(to) {
+			return false, nil
+		}
+		p.Status = to
+		if providerRef != "" {
+			p.ProviderRef = providerRef
+		}
+		if callback != nil {
+			p.Callback = callback
+		}
+		p.UpdatedAt = time.Now()
+		switch to {
+		case PaymentPaid:
+			p.PaidAt = p.UpdatedAt
+		case PaymentRefunded:
+			p.RefundedAt = p.UpdatedAt
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+type memoryUserRepository struct {
+	mu     sync.RWMutex
+	users  []User
+	nextID int
+}
+
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepository{nextID: 1}
+}
+
+func (r *memoryUserRepository) Create(u User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Email, u.Email) {
+			return ErrEmailExists
+		}
+	}
+
+	u.ID = r.nextID
+	r.nextID++
+
+	if u.Role == "" {
+		u.Role = "user"
+	}
+	u.CreatedAt = time.Now()
+
+	r.users = append(r.users, u)
+	return nil
+}
+
+func (r *memoryUserRepository) GetByEmail(email string) (User, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) {
+			return u, true, nil
+		}
+	}
+	return User{}, false, nil
+}
+
+func (r *memoryUserRepository) BumpTokenVersion(email string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		if strings.EqualFold(r.users[i].Email, email) {
+			r.users[i].TokenVersion++
+			return r.users[i].TokenVersion, nil
+		}
+	}
+	return 0, errors.New("user not found")
+}
+
+func (r *memoryUserRepository) MarkEmailVerified(email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		if strings.EqualFold(r.users[i].Email, email) && !r.users[i].EmailVerified {
+			r.users[i].EmailVerified = true
+			r.users[i].VerifiedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(email string, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		if strings.EqualFold(r.users[i].Email, email) {
+			r.users[i].Password = hash
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+func (r *memoryUserRepository) List() ([]User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]User{}, r.users...), nil
+}
+
+func (r *memoryUserRepository) SetRole(email string, role string, cinemas []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		if strings.EqualFold(r.users[i].Email, email) {
+			r.users[i].Role = role
+			r.users[i].Cinemas = append([]string(nil), cinemas...)
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+func (r *memoryUserRepository) SetTwoFactor(email string, tf TwoFactor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		if strings.EqualFold(r.users[i].Email, email) {
+			tf.RecoveryCodes = append([]string(nil), tf.RecoveryCodes...)
+			r.users[i].TwoFactor = tf
+			return nil
+		}
+	}
+	return errors.New("user not found")
+}
+
+func (r *memoryUserRepository) UseTOTPStep(email string, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		tf := &r.users[i].TwoFactor
+		if strings.EqualFold(r.users[i].Email, email) && tf.Enabled && tf.LastStep < step {
+			tf.LastStep = step
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryUserRepository) UseRecoveryCode(email string, hash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		tf := &r.users[i].TwoFactor
+		if !strings.EqualFold(r.users[i].Email, email) || !tf.Enabled {
+			continue
+		}
+		for j, h := range tf.RecoveryCodes {
+			if h == hash {
+				tf.RecoveryCodes = append(tf.RecoveryCodes[:j:j], tf.RecoveryCodes[j+1:]...)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+type memoryHoldRepository struct {
+	mu    sync.RWMutex
+	holds []SeatHold
+}
+
+func NewMemoryHoldRepository() HoldRepository {
+	return &memoryHoldRepository{}
+}
+
+func (r *memoryHoldRepository) Create(h SeatHold) (SeatHold, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if h.ID.IsZero() {
+		h.ID = primitive.NewObjectID()
+	}
+	h.CreatedAt = time.Now()
+	h.UpdatedAt = h.CreatedAt
+	if h.Status == "" {
+		h.Status = HoldActive
+	}
+
+	r.holds = append(r.holds, h)
+	return h, nil
+}
+
+func (r *memoryHoldRepository) GetActive(sessionID int, seat string) (SeatHold, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, h := range r.holds {
+		if h.SessionID == sessionID && h.Seat == seat && h.Status == HoldActive {
+			return h, true, nil
+		}
+	}
+	return SeatHold{}, false, nil
+}
+
+func (r *memoryHoldRepository) GetByOrder(orderID primitive.ObjectID) ([]SeatHold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]SeatHold, 0)
+	for _, h := range r.holds {
+		if h.OrderID == orderID {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryHoldRepository) update(id primitive.ObjectID, fn func(h *SeatHold) bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.holds {
+		if r.holds[i].ID == id {
+			if !fn(&r.holds[i]) {
+				return false
+			}
+			r.holds[i].UpdatedAt = time.Now()
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryHoldRepository) AttachOrder(id primitive.ObjectID, orderID primitive.ObjectID) (bool, error) {
+	return r.update(id, func(h *SeatHold) bool {
+		if h.Status != HoldActive || (!h.OrderID.IsZero() && h.OrderID != orderID) {
+			return false
+		}
+		h.OrderID = orderID
+		return true
+	}), nil
+}
+
+func (r *memoryHoldRepository) Extend(id primitive.ObjectID, expiresAt time.Time) error {
+	r.update(id, func(h *SeatHold) bool {
+		if h.Status != HoldActive {
+			return false
+		}
+		h.ExpiresAt = expiresAt
+		return true
+	})
+	return nil
+}
+
+func (r *memoryHoldRepository) Transition(id primitive.ObjectID, from, to HoldStatus) (bool, error) {
+	return r.update(id, func(h *SeatHold) bool {
+		if h.Status != from {
+			return false
+		}
+		h.Status = to
+		return true
+	}), nil
+}
+
+func (r *memoryHoldRepository) ListExpired(now time.Time) ([]SeatHold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]SeatHold, 0)
+	for _, h := range r.holds {
+		if h.Status == HoldActive && h.ExpiresAt.Before(now) {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (r *memoryHoldRepository) ListActiveBySession(sessionID int) ([]SeatHold, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]SeatHold, 0)
+	for _, h := range r.holds {
+		if h.SessionID == sessionID && h.Status == HoldActive {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+type memoryHallRepository struct {
+	mu     sync.RWMutex
+	halls  []Hall
+	nextID int
+}
+
+func NewMemoryHallRepository() HallRepository {
+	return &memoryHallRepository{nextID: 1}
+}
+
+func (r *memoryHallRepository) Add(h Hall) (Hall, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h.ID = r.nextID
+	r.nextID++
+
+	r.halls = append(r.halls, h)
+	return h, nil
+}
+
+func (r *memoryHallRepository) GetByID(id int) (Hall, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, h := range r.halls {
+		if h.ID == id {
+			return h, true, nil
+		}
+	}
+	return Hall{}, false, nil
+}
+
+func (r *memoryHallRepository) List(cinema string) ([]Hall, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Hall, 0)
+	for _, h := range r.halls {
+		if cinema == "" || h.CinemaName == cinema {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+type memoryCinemaRepository struct {
+	mu      sync.RWMutex
+	cinemas []Cinema
+	nextID  int
+}
+
+func NewMemoryCinemaRepository() CinemaRepository {
+	return &memoryCinemaRepository{nextID: 1}
+}
+
+func (r *memoryCinemaRepository) Create(c Cinema) (Cinema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.cinemas {
+		if existing.Name == c.Name {
+			return Cinema{}, ErrCinemaExists
+		}
+	}
+	c.ID = r.nextID
+	r.nextID++
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	r.cinemas = append(r.cinemas, c)
+	return c, nil
+}
+
+func (r *memoryCinemaRepository) GetByID(id int) (Cinema, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.cinemas {
+		if c.ID == id {
+			return c, true, nil
+		}
+	}
+	return Cinema{}, false, nil
+}
+
+func (r *memoryCinemaRepository) GetByName(name string) (Cinema, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.cinemas {
+		if c.Name == name {
+			return c, true, nil
+		}
+	}
+	return Cinema{}, false, nil
+}
+
+func (r *memoryCinemaRepository) List(city string) ([]Cinema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Cinema, 0, len(r.cinemas))
+	for _, c := range r.cinemas {
+		if city == "" || strings.EqualFold(c.City, city) {
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func (r *memoryCinemaRepository) Update(c Cinema) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.cinemas {
+		if r.cinemas[i].ID == c.ID {
+			r.cinemas[i] = c
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryCinemaRepository) Delete(id int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.cinemas {
+		if r.cinemas[i].ID == id {
+			r.cinemas = append(r.cinemas[:i], r.cinemas[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryPricingRuleRepository struct {
+	mu     sync.RWMutex
+	rules  []service.PricingRule
+	nextID int
+}
+
+func NewMemoryPricingRuleRepository() PricingRuleRepository {
+	return &memoryPricingRuleRepository{nextID: 1}
+}
+
+func (r *memoryPricingRuleRepository) List() ([]service.PricingRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]service.PricingRule, len(r.rules))
+	copy(out, r.rules)
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Priority != out[j].Priority {
+			return out[i].Priority < out[j].Priority
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (r *memoryPricingRuleRepository) Add(rule service.PricingRule) (service.PricingRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rule.ID = r.nextID
+	r.nextID++
+
+	r.rules = append(r.rules, rule)
+	return rule, nil
+}
+
+func (r *memoryPricingRuleRepository) Update(rule service.PricingRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rules {
+		if r.rules[i].ID == rule.ID {
+			r.rules[i] = rule
+			return nil
+		}
+	}
+	return errors.New("rule not found")
+}
+
+func (r *memoryPricingRuleRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.rules {
+		if r.rules[i].ID == id {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("rule not found")
+}
+
+type memoryPromoRepository struct {
+	mu     sync.Mutex
+	promos []PromoCode
 }
 
-func GetAllSessions() []Session {
-	mu.RLock()
-	defer mu.RUnlock()
+func NewMemoryPromoRepository() PromoRepository {
+	return &memoryPromoRepository{}
+}
+
+func clonePromo(p PromoCode) PromoCode {
+	p.Redemptions = append([]PromoRedemption(nil), p.Redemptions...)
+	return p
+}
+
+func (r *memoryPromoRepository) Create(p PromoCode) (PromoCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.promos {
+		if existing.Code == p.Code {
+			return PromoCode{}, ErrPromoExists
+		}
+	}
+	p.CreatedAt = time.Now()
+	p.UsedCount = 0
+	p.Redemptions = []PromoRedemption{}
+
+	r.promos = append(r.promos, p)
+	return clonePromo(p), nil
+}
+
+func (r *memoryPromoRepository) Get(code string) (PromoCode, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, p := range r.promos {
+		if p.Code == code {
+			return clonePromo(p), true, nil
+		}
+	}
+	return PromoCode{}, false, nil
+}
+
+func (r *memoryPromoRepository) List() ([]PromoCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]PromoCode, 0, len(r.promos))
+	for i := len(r.promos) - 1; i >= 0; i-- {
+		out = append(out, clonePromo(r.promos[i]))
+	}
+	return out, nil
+}
+
+func (r *memoryPromoRepository) SetActive(code string, active bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.promos {
+		if r.promos[i].Code == code {
+			r.promos[i].Active = active
+			return nil
+		}
+	}
+	return ErrPromoNotFound
+}
+
+func (r *memoryPromoRepository) Redeem(code string, email string, orderID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.promos {
+		p := &r.promos[i]
+		if p.Code != code {
+			continue
+		}
+		if !p.Active {
+			return ErrPromoInactive
+		}
+		if p.MaxUses > 0 && p.UsedCount >= p.MaxUses {
+			return ErrPromoExhausted
+		}
+		if p.MaxUsesPerUser > 0 && p.usesBy(email) >= p.MaxUsesPerUser {
+			return ErrPromoExhausted
+		}
+		p.UsedCount++
+		p.Redemptions = append(p.Redemptions, PromoRedemption{Email: email, OrderID: orderID, RedeemedAt: time.Now()})
+		return nil
+	}
+	return ErrPromoNotFound
+}
+
+func (r *memoryPromoRepository) Release(code string, orderID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.promos {
+		p := &r.promos[i]
+		if p.Code != code {
+			continue
+		}
+		for j, red := range p.Redemptions {
+			if red.OrderID == orderID {
+				p.Redemptions = append(p.Redemptions[:j], p.Redemptions[j+1:]...)
+				p.UsedCount--
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+type memoryLedgerRepository struct {
+	mu      sync.Mutex
+	entries []LedgerEntry
+}
+
+func NewMemoryLedgerRepository() LedgerRepository {
+	return &memoryLedgerRepository{}
+}
 
-	out := make([]Session, len(sessions))
-	copy(out, sessions)
+func (r *memoryLedgerRepository) appendLocked(e LedgerEntry) LedgerEntry {
+	e.ID = primitive.NewObjectID()
+	e.CreatedAt = time.Now()
+	r.entries = append(r.entries, e)
+	return e
+}
+
+func (r *memoryLedgerRepository) balanceLocked(email string) int {
+	total := 0
+	for _, e := range r.entries {
+		if e.Email == email {
+			total += e.Amount
+		}
+	}
+	return total
+}
+
+func (r *memoryLedgerRepository) Append(e LedgerEntry) (LedgerEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e.Type == LedgerEarn && !e.OrderID.IsZero() {
+		for _, existing := range r.entries {
+			if existing.Type == LedgerEarn && existing.OrderID == e.OrderID {
+				return LedgerEntry{}, ErrAlreadyCredited
+			}
+		}
+	}
+	if e.Amount < 0 {
+		e.Amount = clampDeduction(r.balanceLocked(e.Email), e.Amount)
+	}
+	return r.appendLocked(e), nil
+}
+
+func (r *memoryLedgerRepository) Spend(email string, amount int, orderID primitive.ObjectID) (LedgerEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.balanceLocked(email) < amount {
+		return LedgerEntry{}, ErrInsufficientBonuses
+	}
+	return r.appendLocked(LedgerEntry{
+		Email:   email,
+		Type:    LedgerSpend,
+		Amount:  -amount,
+		OrderID: orderID,
+		Note:    "Order " + orderID.Hex() + " checkout",
+	}), nil
+}
+
+func (r *memoryLedgerRepository) Balance(email string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.balanceLocked(email), nil
+}
+
+func (r *memoryLedgerRepository) filter(keep func(e LedgerEntry) bool) []LedgerEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]LedgerEntry, 0)
+	for _, e := range r.entries {
+		if keep(e) {
+			out = append(out, e)
+		}
+	}
 	return out
 }
+
+func (r *memoryLedgerRepository) History(email string) ([]LedgerEntry, error) {
+	return r.filter(func(e LedgerEntry) bool { return e.Email == email }), nil
+}
+
+func (r *memoryLedgerRepository) ListByOrder(orderID primitive.ObjectID) ([]LedgerEntry, error) {
+	return r.filter(func(e LedgerEntry) bool { return e.OrderID == orderID }), nil
+}
+
+func (r *memoryLedgerRepository) AccountsWithExpiredEarnings(now time.Time) ([]string, error) {
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	for _, e := range r.filter(func(e LedgerEntry) bool {
+		return e.Type == LedgerEarn && !e.ExpiresAt.IsZero() && !e.ExpiresAt.After(now)
+	}) {
+		if !seen[e.Email] {
+			seen[e.Email] = true
+			out = append(out, e.Email)
+		}
+	}
+	return out, nil
+}
+
+type memoryReconciliationRepository struct {
+	mu      sync.RWMutex
+	reports []ReconciliationReport
+}
+
+func NewMemoryReconciliationRepository() ReconciliationRepository {
+	return &memoryReconciliationRepository{}
+}
+
+func (r *memoryReconciliationRepository) Save(rep ReconciliationReport) (ReconciliationReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rep.CreatedAt = time.Now()
+	for i := range r.reports {
+		if r.reports[i].Date == rep.Date {
+			rep.ID = r.reports[i].ID
+			r.reports[i] = rep
+			return rep, nil
+		}
+	}
+	rep.ID = primitive.NewObjectID()
+	r.reports = append(r.reports, rep)
+	return rep, nil
+}
+
+func (r *memoryReconciliationRepository) GetByDate(date string) (ReconciliationReport, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rep := range r.reports {
+		if rep.Date == date {
+			return rep, true, nil
+		}
+	}
+	return ReconciliationReport{}, false, nil
+}
+
+func (r *memoryReconciliationRepository) List() ([]ReconciliationReport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]ReconciliationReport, len(r.reports))
+	copy(out, r.reports)
+	sort.Slice(out, func(i, j int) bool { return out[i].Date > out[j].Date })
+	return out, nil
+}
+
+type memoryRefreshTokenRepository struct {
+	mu     sync.RWMutex
+	tokens []RefreshToken
+}
+
+func NewMemoryRefreshTokenRepository() RefreshTokenRepository {
+	return &memoryRefreshTokenRepository{}
+}
+
+func (r *memoryRefreshTokenRepository) Create(t RefreshToken) (RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t.ID.IsZero() {
+		t.ID = primitive.NewObjectID()
+	}
+	t.CreatedAt = time.Now()
+	r.tokens = append(r.tokens, t)
+	return t, nil
+}
+
+func (r *memoryRefreshTokenRepository) GetByHash(hash string) (RefreshToken, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.tokens {
+		if t.TokenHash == hash {
+			return t, true, nil
+		}
+	}
+	return RefreshToken{}, false, nil
+}
+
+func (r *memoryRefreshTokenRepository) MarkUsed(id primitive.ObjectID, replacedBy primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.tokens {
+		t := &r.tokens[i]
+		if t.ID != id {
+			continue
+		}
+		if !t.UsedAt.IsZero() || !t.RevokedAt.IsZero() {
+			return false, nil
+		}
+		t.UsedAt = time.Now()
+		t.ReplacedBy = replacedBy
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *memoryRefreshTokenRepository) RevokeFamily(familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for i := range r.tokens {
+		if r.tokens[i].FamilyID == familyID && r.tokens[i].RevokedAt.IsZero() {
+			r.tokens[i].RevokedAt = now
+		}
+	}
+	return nil
+}
+
+func (r *memoryRefreshTokenRepository) RevokeAllForUser(email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for i := range r.tokens {
+		if r.tokens[i].Email == email && r.tokens[i].RevokedAt.IsZero() {
+			r.tokens[i].RevokedAt = now
+		}
+	}
+	return nil
+}
+
+type memoryRevokedTokenRepository struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+func NewMemoryRevokedTokenRepository() RevokedTokenRepository {
+	return &memoryRevokedTokenRepository{revoked: make(map[string]time.Time)}
+}
+
+func (r *memoryRevokedTokenRepository) Revoke(jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, exp := range r.revoked {
+		if exp.Before(now) {
+			delete(r.revoked, id)
+		}
+	}
+	r.revoked[jti] = expiresAt
+	return nil
+}
+
+func (r *memoryRevokedTokenRepository) IsRevoked(jti string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+type memoryActionTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]ActionToken
+}
+
+func NewMemoryActionTokenRepository() ActionTokenRepository {
+	return &memoryActionTokenRepository{tokens: make(map[string]ActionToken)}
+}
+
+func (r *memoryActionTokenRepository) Create(t ActionToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.CreatedAt = time.Now()
+	r.tokens[t.JTI] = t
+	return nil
+}
+
+func (r *memoryActionTokenRepository) Consume(jti string, purpose string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tokens[jti]
+	if !ok || t.Purpose != purpose || !t.UsedAt.IsZero() || time.Now().After(t.ExpiresAt) {
+		return false, nil
+	}
+	t.UsedAt = time.Now()
+	r.tokens[jti] = t
+	return true, nil
+}
+
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]LoginAttempt
+}
+
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: make(map[string]LoginAttempt)}
+}
+
+func (r *memoryLoginAttemptRepository) Get(key string) (LoginAttempt, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.attempts[key]
+	return a, ok, nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(key string, now time.Time, window time.Duration) (LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.attempts[key]
+	if !ok || a.LastFailure.Before(now.Add(-window)) {
+		a = LoginAttempt{Key: key, LockedUntil: a.LockedUntil}
+	}
+	a.Failures++
+	a.LastFailure = now
+	r.attempts[key] = a
+
+	if len(r.attempts) > 10000 {
+		for k, old := range r.attempts {
+			if old.LastFailure.Before(now.Add(-window)) && old.LockedUntil.Before(now) {
+				delete(r.attempts, k)
+			}
+		}
+	}
+	return a, nil
+}
+
+func (r *memoryLoginAttemptRepository) Lock(key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a := r.attempts[key]
+	a.Key = key
+	a.LockedUntil = until
+	r.attempts[key] = a
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) Reset(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+type memoryMovieRepository struct {
+	mu     sync.RWMutex
+	movies map[int]Movie
+}
+
+func NewMemoryMovieRepository() MovieRepository {
+	return &memoryMovieRepository{movies: make(map[int]Movie)}
+}
+
+func (r *memoryMovieRepository) Upsert(m Movie) (Movie, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.movies[m.ID] = m
+	return m, nil
+}
+
+func (r *memoryMovieRepository) GetByID(id int) (Movie, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.movies[id]
+	return m, ok, nil
+}
+
+func (r *memoryMovieRepository) List() ([]Movie, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	movies := make([]Movie, 0, len(r.movies))
+	for _, m := range r.movies {
+		movies = append(movies, m)
+	}
+	sort.Slice(movies, func(i, j int) bool { return movies[i].Title < movies[j].Title })
+	return movies, nil
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"cinema/internal/service"
+)
+
+type SessionFilter struct {
+	Cinemas          []string
+	Date             string
+	MaxPrice         float64
+	OnlyWithSeats    bool
+	IncludeCancelled bool
+}
+
+func (f SessionFilter) byDate() bool {
+	return f.Date != "" && f.Date != "all"
+}
+
+func (f SessionFilter) matches(s Session) bool {
+	if len(f.Cinemas) > 0 && !slices.Contains(f.Cinemas, s.CinemaName) {
+		return false
+	}
+	if f.byDate() && s.LocalDate() != f.Date {
+		return false
+	}
+	if f.MaxPrice > 0 && s.BasePrice > f.MaxPrice {
+		return false
+	}
+	if f.OnlyWithSeats && len(s.AvailableSeats) == 0 {
+		return false
+	}
+	return f.IncludeCancelled || s.Status != SessionCancelled
+}
+
+// Each session is matched against the calendar day of its own cinema, so the
+// UTC range covering that day anywhere on Earth is used to narrow the query.
+func sessionDayWindow(date string) (time.Time, time.Time, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date format: %v", err)
+	}
+	return day.Add(-14 * time.Hour), day.Add(36 * time.Hour), nil
+}
+
+func ListSessions(repos Repositories, cinema, city string, f SessionFilter) ([]Session, error) {
+	if cinema != "" {
+		f.Cinemas = []string{cinema}
+	}
+	if city != "" {
+		cinemas, err := repos.Cinemas.List(city)
+		if err != nil {
+			return nil, err
+		}
+		f.Cinemas = nil
+		for _, c := range cinemas {
+			if cinema == "" || c.Name == cinema {
+				f.Cinemas = append(f.Cinemas, c.Name)
+			}
+		}
+		if len(f.Cinemas) == 0 {
+			return []Session{}, nil
+		}
+	}
+	return repos.Sessions.Filter(f)
+}
+
+func (s Session) Location() *time.Location {
+	return service.LoadLocation(s.Timezone)
+}
+
+func (s Session) LocalStart() time.Time {
+	return s.StartTime.In(s.Location())
+}
+
+func (s Session) LocalDate() string {
+	return s.LocalStart().Format("2006-01-02")
+}
+
+func (s Session) MarshalJSON() ([]byte, error) {
+	type session Session
+	loc := s.Location()
+	return json.Marshal(struct {
+		session
+		StartTime      time.Time `json:"start_time"`
+		StartTimeLocal string    `json:"start_time_local"`
+		EndTime        time.Time `json:"end_time,omitempty"`
+		EndTimeLocal   string    `json:"end_time_local,omitempty"`
+		Timezone       string    `json:"timezone"`
+		UTCOffset      string    `json:"utc_offset"`
+	}{
+		session:        session(s),
+		StartTime:      s.StartTime.UTC(),
+		StartTimeLocal: service.LocalTime(s.StartTime, loc.String()),
+		EndTime:        s.EndTime.UTC(),
+		EndTimeLocal:   service.LocalTime(s.EndTime, loc.String()),
+		Timezone:       loc.String(),
+		UTCOffset:      s.StartTime.In(loc).Format("-07:00"),
+	})
+}
+
+func (o Order) MarshalJSON() ([]byte, error) {
+	type order Order
+	loc := service.LoadLocation(o.Timezone)
+	return json.Marshal(struct {
+		order
+		StartTime      time.Time `json:"start_time"`
+		StartTimeLocal string    `json:"start_time_local"`
+		Timezone       string    `json:"timezone"`
+		UTCOffset      string    `json:"utc_offset"`
+	}{
+		order:          order(o),
+		StartTime:      o.StartTime.UTC(),
+		StartTimeLocal: service.LocalTime(o.StartTime, loc.String()),
+		Timezone:       loc.String(),
+		UTCOffset:      o.StartTime.In(loc).Format("-07:00"),
+	})
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RefreshToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email      string             `bson:"email" json:"email"`
+	FamilyID   string             `bson:"family_id" json:"family_id"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UsedAt     time.Time          `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	RevokedAt  time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ReplacedBy primitive.ObjectID `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"`
+	MFA        bool               `bson:"mfa,omitempty" json:"mfa"`
+}
+
+type AuthTokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
+func issueRefreshToken(repos Repositories, email, familyID string, mfa bool) (RefreshToken, string, error) {
+	raw, hash, err := service.NewRefreshToken()
+	if err != nil {
+		return RefreshToken{}, "", err
+	}
+	if familyID == "" {
+		familyID = primitive.NewObjectID().Hex()
+	}
+	t, err := repos.RefreshTokens.Create(RefreshToken{
+		ID:        primitive.NewObjectID(),
+		Email:     email,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(service.RefreshTokenTTL()),
+		MFA:       mfa,
+	})
+	return t, raw, err
+}
+
+func IssueAuthTokens(repos Repositories, u User, mfa bool) (AuthTokens, error) {
+	access, err := service.GenerateJWT(u.Email, u.Username, u.Role, u.Cinemas, u.TokenVersion, mfa)
+	if err != nil {
+		return AuthTokens{}, err
+	}
+	_, refresh, err := issueRefreshToken(repos, u.Email, "", mfa)
+	if err != nil {
+		return AuthTokens{}, err
+	}
+	return AuthTokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(service.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+func RotateRefreshToken(repos Repositories, raw string) (User, AuthTokens, error) {
+	current, ok, err := repos.RefreshTokens.GetByHash(service.HashRefreshToken(raw))
+	if err != nil {
+		return User{}, AuthTokens{}, err
+	}
+	if !ok || !current.RevokedAt.IsZero() || time.Now().After(current.ExpiresAt) {
+		return User{}, AuthTokens{}, ErrRefreshTokenInvalid
+	}
+	if !current.UsedAt.IsZero() {
+		_ = repos.RefreshTokens.RevokeFamily(current.FamilyID)
+		return User{}, AuthTokens{}, ErrRefreshTokenReused
+	}
+
+	u, ok, err := repos.Users.GetByEmail(current.Email)
+	if err != nil {
+		return User{}, AuthTokens{}, err
+	}
+	if !ok {
+		return User{}, AuthTokens{}, ErrRefreshTokenInvalid
+	}
+
+	next, refresh, err := issueRefreshToken(repos, u.Email, current.FamilyID, current.MFA)
+	if err != nil {
+		return User{}, AuthTokens{}, err
+	}
+	used, err := repos.RefreshTokens.MarkUsed(current.ID, next.ID)
+	if err != nil {
+		return User{}, AuthTokens{}, err
+	}
+	if !used {
+		_ = repos.RefreshTokens.RevokeFamily(current.FamilyID)
+		return User{}, AuthTokens{}, ErrRefreshTokenReused
+	}
+
+	access, err := service.GenerateJWT(u.Email, u.Username, u.Role, u.Cinemas, u.TokenVersion, current.MFA)
+	if err != nil {
+		return User{}, AuthTokens{}, err
+	}
+	return u, AuthTokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(service.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+func RevokeRefreshToken(repos Repositories, raw string, email string) error {
+	t, ok, err := repos.RefreshTokens.GetByHash(service.HashRefreshToken(raw))
+	if err != nil || !ok || t.Email != email {
+		return err
+	}
+	return repos.RefreshTokens.RevokeFamily(t.FamilyID)
+}
+
+func RevokeAccessToken(repos Repositories, claims *service.JWTClaim) error {
+	if claims == nil || claims.Id == "" {
+		return nil
+	}
+	return repos.RevokedTokens.Revoke(claims.Id, time.Unix(claims.ExpiresAt, 0))
+}
+
+func RevokeAllSessions(repos Repositories, email string) error {
+	if _, err := repos.Users.BumpTokenVersion(email); err != nil {
+		return err
+	}
+	return repos.RefreshTokens.RevokeAllForUser(email)
+}
+
+func NewTokenChecker(repos Repositories) service.TokenChecker {
+	return func(claims *service.JWTClaim) error {
+		if claims.Id != "" {
+			revoked, err := repos.RevokedTokens.IsRevoked(claims.Id)
+			if err != nil {
+				return err
+			}
+			if revoked {
+				return service.ErrTokenRevoked
+			}
+		}
+		u, ok, err := repos.Users.GetByEmail(claims.Email)
+		if err != nil {
+			return err
+		}
+		if !ok || u.TokenVersion != claims.Version {
+			return service.ErrTokenRevoked
+		}
+		return nil
+	}
+}
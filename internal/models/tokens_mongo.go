@@ -0,0 +1,114 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRefreshTokenRepository struct{}
+
+func NewMongoRefreshTokenRepository() RefreshTokenRepository {
+	return &mongoRefreshTokenRepository{}
+}
+
+func (r *mongoRefreshTokenRepository) Create(t RefreshToken) (RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if t.ID.IsZero() {
+		t.ID = primitive.NewObjectID()
+	}
+	t.CreatedAt = time.Now()
+	if _, err := service.RefreshTokensCollection().InsertOne(ctx, t); err != nil {
+		return RefreshToken{}, err
+	}
+	return t, nil
+}
+
+func (r *mongoRefreshTokenRepository) GetByHash(hash string) (RefreshToken, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var t RefreshToken
+	err := service.RefreshTokensCollection().FindOne(ctx, bson.M{"token_hash": hash}).Decode(&t)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return RefreshToken{}, false, nil
+		}
+		return RefreshToken{}, false, err
+	}
+	return t, true, nil
+}
+
+func (r *mongoRefreshTokenRepository) MarkUsed(id primitive.ObjectID, replacedBy primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":        id,
+		"used_at":    bson.M{"$exists": false},
+		"revoked_at": bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"used_at": time.Now(), "replaced_by": replacedBy}}
+	res, err := service.RefreshTokensCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *mongoRefreshTokenRepository) revoke(filter bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter["revoked_at"] = bson.M{"$exists": false}
+	_, err := service.RefreshTokensCollection().UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	return err
+}
+
+func (r *mongoRefreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.revoke(bson.M{"family_id": familyID})
+}
+
+func (r *mongoRefreshTokenRepository) RevokeAllForUser(email string) error {
+	return r.revoke(bson.M{"email": email})
+}
+
+type mongoRevokedTokenRepository struct{}
+
+func NewMongoRevokedTokenRepository() RevokedTokenRepository {
+	return &mongoRevokedTokenRepository{}
+}
+
+func (r *mongoRevokedTokenRepository) Revoke(jti string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	col := service.RevokedTokensCollection()
+	_, _ = col.DeleteMany(ctx, bson.M{"expires_at": bson.M{"$lt": time.Now()}})
+	_, err := col.UpdateOne(ctx,
+		bson.M{"jti": jti},
+		bson.M{"$set": bson.M{"jti": jti, "expires_at": expiresAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *mongoRevokedTokenRepository) IsRevoked(jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := service.RevokedTokensCollection().CountDocuments(ctx, bson.M{"jti": jti})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
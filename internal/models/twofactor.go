@@ -0,0 +1,144 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"cinema/internal/service"
+)
+
+var (
+	ErrTwoFactorInvalid        = errors.New("invalid two-factor code")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication is not set up")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorMandatory      = errors.New("two-factor authentication is required for this role")
+)
+
+func BeginTwoFactorSetup(repos Repositories, u User) (string, string, error) {
+	if u.TwoFactor.Enabled {
+		return "", "", ErrTwoFactorAlreadyEnabled
+	}
+	secret, err := service.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := repos.Users.SetTwoFactor(u.Email, TwoFactor{Secret: secret}); err != nil {
+		return "", "", err
+	}
+	return secret, service.TOTPProvisioningURI(secret, u.Email), nil
+}
+
+func EnableTwoFactor(repos Repositories, u User, code string) ([]string, error) {
+	if u.TwoFactor.Enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if u.TwoFactor.Secret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	step, ok := service.VerifyTOTP(u.TwoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorInvalid
+	}
+	codes, hashes, err := service.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = repos.Users.SetTwoFactor(u.Email, TwoFactor{
+		Enabled:       true,
+		Secret:        u.TwoFactor.Secret,
+		RecoveryCodes: hashes,
+		LastStep:      step,
+		EnabledAt:     time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func CheckTwoFactor(repos Repositories, u User, code string) error {
+	if !u.TwoFactor.Enabled {
+		return ErrTwoFactorNotEnrolled
+	}
+	if step, ok := service.VerifyTOTP(u.TwoFactor.Secret, code, time.Now()); ok {
+		fresh, err := repos.Users.UseTOTPStep(u.Email, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrTwoFactorInvalid
+		}
+		return nil
+	}
+	used, err := repos.Users.UseRecoveryCode(u.Email, service.HashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrTwoFactorInvalid
+	}
+	return nil
+}
+
+func DisableTwoFactor(repos Repositories, u User, code string) error {
+	if service.TwoFactorRequired(u.Role) {
+		return ErrTwoFactorMandatory
+	}
+	if err := CheckTwoFactor(repos, u, code); err != nil {
+		return err
+	}
+	return repos.Users.SetTwoFactor(u.Email, TwoFactor{})
+}
+
+func RegenerateRecoveryCodes(repos Repositories, u User, code string) ([]string, error) {
+	if err := CheckTwoFactor(repos, u, code); err != nil {
+		return nil, err
+	}
+	fresh, ok, err := repos.Users.GetByEmail(u.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	codes, hashes, err := service.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	tf := fresh.TwoFactor
+	tf.RecoveryCodes = hashes
+	if err := repos.Users.SetTwoFactor(u.Email, tf); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func StartMFALogin(repos Repositories, u User) (string, error) {
+	return issueActionToken(repos, u.Email, service.PurposeLoginMFA, service.LoginMFATokenTTL)
+}
+
+func CompleteMFALogin(repos Repositories, token, code string) (User, AuthTokens, error) {
+	claims, err := service.ParseActionToken(token, service.PurposeLoginMFA)
+	if err != nil {
+		return User{}, AuthTokens{}, err
+	}
+	u, ok, err := repos.Users.GetByEmail(claims.Email)
+	if err != nil {
+		return User{}, AuthTokens{}, err
+	}
+	if !ok {
+		return User{}, AuthTokens{}, service.ErrActionTokenInvalid
+	}
+	if err := CheckTwoFactor(repos, u, code); err != nil {
+		return u, AuthTokens{}, err
+	}
+	consumed, err := repos.ActionTokens.Consume(claims.Id, service.PurposeLoginMFA)
+	if err != nil {
+		return u, AuthTokens{}, err
+	}
+	if !consumed {
+		return u, AuthTokens{}, service.ErrActionTokenInvalid
+	}
+	tokens, err := IssueAuthTokens(repos, u, true)
+	return u, tokens, err
+}
@@ -3,13 +3,13 @@ package models
 import (
 	"context"
 	"errors"
-	"log"
 	"time"
 
 	"cinema/internal/service"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type User struct {
@@ -19,19 +19,44 @@ type User struct {
 	Password  string    `json:"-" bson:"password"`
 	Role      string    `json:"role" bson:"role"`
 	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+
+	Cinemas []string `json:"cinemas,omitempty" bson:"cinemas,omitempty"`
+
+	TokenVersion  int       `json:"-" bson:"token_version"`
+	EmailVerified bool      `json:"email_verified" bson:"email_verified"`
+	VerifiedAt    time.Time `json:"verified_at,omitempty" bson:"verified_at,omitempty"`
+
+	TwoFactor TwoFactor `json:"two_factor" bson:"two_factor"`
+}
+
+type TwoFactor struct {
+	Enabled       bool      `json:"enabled" bson:"enabled"`
+	Secret        string    `json:"-" bson:"secret,omitempty"`
+	RecoveryCodes []string  `json:"-" bson:"recovery_codes,omitempty"`
+	LastStep      int64     `json:"-" bson:"last_step,omitempty"`
+	EnabledAt     time.Time `json:"enabled_at,omitempty" bson:"enabled_at,omitempty"`
 }
 
-func UsersCollection() *mongo.Collection {
-	return service.MongoDB.Collection("users")
+var ErrEmailExists = errors.New("email already exists")
+
+// emailCollation matches emails case-insensitively so accounts stored before
+// addresses were normalised can still be found by their lowercased form.
+var emailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+type mongoUserRepository struct{}
+
+func NewMongoUserRepository() UserRepository {
+	return &mongoUserRepository{}
 }
 
-func CreateUser(u User) error {
+func (r *mongoUserRepository) Create(u User) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	count, _ := UsersCollection().CountDocuments(ctx, bson.M{"email": u.Email})
+	count, _ := service.UsersCollection().CountDocuments(ctx, bson.M{"email": u.Email},
+		options.Count().SetCollation(emailCollation))
 	if count > 0 {
-		return errors.New("email already exists")
+		return ErrEmailExists
 	}
 
 	id, err := nextID("users")
@@ -46,16 +71,23 @@ func CreateUser(u User) error {
 
 	u.CreatedAt = time.Now()
 
-	_, err = UsersCollection().InsertOne(ctx, u)
-	return err
+	if _, err := service.UsersCollection().InsertOne(ctx, u); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrEmailExists
+		}
+		return err
+	}
+	return nil
 }
 
-func GetUserByEmail(email string) (User, bool, error) {
+func (r *mongoUserRepository) GetByEmail(email string) (User, bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	var u User
-	err := UsersCollection().FindOne(ctx, bson.M{"email": email}).Decode(&u)
+	err := service.UsersCollection().
+		FindOne(ctx, bson.M{"email": email}, options.FindOne().SetCollation(emailCollation)).
+		Decode(&u)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return User{}, false, nil
@@ -63,7 +95,136 @@ func GetUserByEmail(email string) (User, bool, error) {
 		return User{}, false, err
 	}
 
-	log.Printf("DEBUG: Found user %s with role [%s]", u.Email, u.Role)
-
 	return u, true, nil
 }
+
+func (r *mongoUserRepository) BumpTokenVersion(email string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var u User
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetCollation(emailCollation)
+	err := service.UsersCollection().
+		FindOneAndUpdate(ctx, bson.M{"email": email}, bson.M{"$inc": bson.M{"token_version": 1}}, opts).
+		Decode(&u)
+	if err != nil {
+		return 0, err
+	}
+	return u.TokenVersion, nil
+}
+
+func (r *mongoUserRepository) MarkEmailVerified(email string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := service.UsersCollection().UpdateOne(ctx,
+		bson.M{"email": email, "email_verified": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"email_verified": true, "verified_at": time.Now()}},
+		options.Update().SetCollation(emailCollation),
+	)
+	return err
+}
+
+func (r *mongoUserRepository) UpdatePassword(email string, hash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.UsersCollection().UpdateOne(ctx,
+		bson.M{"email": email},
+		bson.M{"$set": bson.M{"password": hash}},
+		options.Update().SetCollation(emailCollation),
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) List() ([]User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := service.UsersCollection().Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *mongoUserRepository) SetRole(email string, role string, cinemas []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.UsersCollection().UpdateOne(ctx,
+		bson.M{"email": email},
+		bson.M{"$set": bson.M{"role": role, "cinemas": cinemas}},
+		options.Update().SetCollation(emailCollation),
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) SetTwoFactor(email string, tf TwoFactor) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.UsersCollection().UpdateOne(ctx,
+		bson.M{"email": email},
+		bson.M{"$set": bson.M{"two_factor": tf}},
+		options.Update().SetCollation(emailCollation),
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func (r *mongoUserRepository) UseTOTPStep(email string, step int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.UsersCollection().UpdateOne(ctx,
+		bson.M{"email": email, "two_factor.enabled": true, "$or": bson.A{
+			bson.M{"two_factor.last_step": bson.M{"$lt": step}},
+			bson.M{"two_factor.last_step": bson.M{"$exists": false}},
+		}},
+		bson.M{"$set": bson.M{"two_factor.last_step": step}},
+		options.Update().SetCollation(emailCollation),
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *mongoUserRepository) UseRecoveryCode(email string, hash string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := service.UsersCollection().UpdateOne(ctx,
+		bson.M{"email": email, "two_factor.enabled": true, "two_factor.recovery_codes": hash},
+		bson.M{"$pull": bson.M{"two_factor.recovery_codes": hash}},
+		options.Update().SetCollation(emailCollation),
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"cinema/internal/service"
+)
+
+// Accounts created before emails were normalised keep their original case;
+// every lookup and write must still reach them through the lowercased address.
+func TestMixedCaseAccountIsReachableByLowercaseEmail(t *testing.T) {
+	repos := NewMemoryRepositories()
+	const stored = "Legacy.User@Example.com"
+	const typed = "legacy.user@example.com"
+	if err := repos.Users.Create(User{Email: stored, Password: "hash"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if err := repos.Users.Create(User{Email: typed}); !errors.Is(err, ErrEmailExists) {
+		t.Fatalf("create lowercase duplicate: err = %v, want ErrEmailExists", err)
+	}
+
+	u, err := AssignRole(repos, typed, service.RoleSuperAdmin, nil)
+	if err != nil {
+		t.Fatalf("AssignRole: %v", err)
+	}
+	if u.Email != stored {
+		t.Fatalf("AssignRole returned %q, want stored email %q", u.Email, stored)
+	}
+	if err := repos.Users.UpdatePassword(typed, "new-hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+	if err := repos.Users.MarkEmailVerified(typed); err != nil {
+		t.Fatalf("MarkEmailVerified: %v", err)
+	}
+	if err := repos.Users.SetTwoFactor(typed, TwoFactor{Enabled: true, RecoveryCodes: []string{"code"}}); err != nil {
+		t.Fatalf("SetTwoFactor: %v", err)
+	}
+	if ok, err := repos.Users.UseTOTPStep(typed, 1); err != nil || !ok {
+		t.Fatalf("UseTOTPStep: ok=%v err=%v", ok, err)
+	}
+	if ok, err := repos.Users.UseRecoveryCode(typed, "code"); err != nil || !ok {
+		t.Fatalf("UseRecoveryCode: ok=%v err=%v", ok, err)
+	}
+
+	got, ok, err := repos.Users.GetByEmail(typed)
+	if err != nil || !ok {
+		t.Fatalf("GetByEmail: ok=%v err=%v", ok, err)
+	}
+	if got.Role != service.RoleSuperAdmin || got.Password != "new-hash" || !got.EmailVerified || got.TokenVersion != 1 {
+		t.Fatalf("user not updated: %+v", got)
+	}
+	if len(got.TwoFactor.RecoveryCodes) != 0 || got.TwoFactor.LastStep != 1 {
+		t.Fatalf("two factor not updated: %+v", got.TwoFactor)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposePasswordReset = "password_reset"
+
+	VerifyEmailTokenTTL   = 24 * time.Hour
+	PasswordResetTokenTTL = time.Hour
+)
+
+var ErrActionTokenInvalid = errors.New("invalid or expired token")
+
+type ActionClaim struct {
+	Email   string `json:"email"`
+	Purpose string `json:"purpose"`
+	jwt.StandardClaims
+}
+
+func actionKey() []byte {
+	mac := hmac.New(sha256.New, jwtKey)
+	mac.Write([]byte("action-tokens"))
+	return mac.Sum(nil)
+}
+
+func GenerateActionToken(email, purpose string, ttl time.Duration) (string, *ActionClaim, error) {
+	jti, err := RandomSecretHash()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	claims := &ActionClaim{
+		Email:   email,
+		Purpose: purpose,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			ExpiresAt: now.Add(ttl).Unix(),
+			IssuedAt:  now.Unix(),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(actionKey())
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+func ParseActionToken(tokenString, purpose string) (*ActionClaim, error) {
+	t, err := jwt.ParseWithClaims(tokenString, &ActionClaim{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return actionKey(), nil
+	})
+	if err != nil {
+		return nil, ErrActionTokenInvalid
+	}
+	claims, ok := t.Claims.(*ActionClaim)
+	if !ok || !t.Valid || claims.Purpose != purpose || claims.Id == "" {
+		return nil, ErrActionTokenInvalid
+	}
+	return claims, nil
+}
+
+func RequireEmailVerification() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+func sendAsync(to, subject, body string) {
+	go func() {
+		if err := SendEmail(to, subject, body); err != nil {
+			log.Println("[EMAIL] send failed:", err)
+		} else {
+			log.Println("[EMAIL] sent to:", to)
+		}
+	}()
+}
+
+func SendVerificationEmail(email, token string) {
+	link := appBaseURL() + "/auth/verify-email?token=" + url.QueryEscape(token)
+	sendAsync(email, "CinemaGo: Confirm your email",
+		"Welcome to CinemaGo!\n"+
+			"Confirm your email address by opening this link within 24 hours:\n"+link)
+}
+
+func SendPasswordResetEmail(email, token string) {
+	link := appBaseURL() + "/pages/reset.html?token=" + url.QueryEscape(token)
+	sendAsync(email, "CinemaGo: Reset your password",
+		"Someone asked to reset your CinemaGo password.\n"+
+			"Open this link within 1 hour to choose a new one:\n"+link+"\n"+
+			"If it wasn't you, ignore this email.")
+}
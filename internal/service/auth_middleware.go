@@ -2,7 +2,7 @@ package service
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
 	"strings"
 )
@@ -10,10 +10,21 @@ import (
 type contextKey string
 
 const (
-	RoleKey  contextKey = "role"
-	EmailKey contextKey = "email"
+	RoleKey   contextKey = "role"
+	EmailKey  contextKey = "email"
+	ClaimsKey contextKey = "claims"
 )
 
+var ErrTokenRevoked = errors.New("token revoked")
+
+type TokenChecker func(claims *JWTClaim) error
+
+var tokenChecker TokenChecker
+
+func SetTokenChecker(fn TokenChecker) {
+	tokenChecker = fn
+}
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -34,23 +45,17 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
+		if tokenChecker != nil {
+			if err := tokenChecker(claims); err != nil {
+				http.Error(w, "Token revoked", http.StatusUnauthorized)
+				return
+			}
+		}
 		ctx := context.WithValue(r.Context(), RoleKey, claims.Role)
 		ctx = context.WithValue(ctx, EmailKey, claims.Email)
+		ctx = context.WithValue(ctx, ClaimsKey, claims)
+		ctx = context.WithValue(ctx, CinemasKey, claims.Cinemas)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
-
-func AdminMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		role, ok := r.Context().Value(RoleKey).(string)
-
-		log.Printf("Checking admin access. Role found in context: '%s'", role)
-
-		if !ok || role != "admin" {
-			http.Error(w, "Forbidden: Admins only", http.StatusForbidden)
-			return
-		}
-		next.ServeHTTP(w, r)
-	})
-}
@@ -1,12 +1,46 @@
 package service
 
-import "log"
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
 
-func CalculatePrice(base float64, isStudent bool) float64 {
-	if isStudent {
-		return base * 0.8
+const (
+	defaultSeatHoldTTL  = 10 * time.Minute
+	defaultCancelCutoff = 2 * time.Hour
+	defaultReconcileAge = 15 * time.Minute
+)
+
+func SeatHoldTTL() time.Duration {
+	if v := os.Getenv("SEAT_HOLD_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid SEAT_HOLD_TTL %q, using %v", v, defaultSeatHoldTTL)
 	}
-	return base
+	return defaultSeatHoldTTL
+}
+
+func CancelCutoff() time.Duration {
+	if v := os.Getenv("CANCEL_CUTOFF"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+		log.Printf("Invalid CANCEL_CUTOFF %q, using %v", v, defaultCancelCutoff)
+	}
+	return defaultCancelCutoff
+}
+
+func PaymentReconcileAfter() time.Duration {
+	if v := os.Getenv("PAYMENT_RECONCILE_AFTER"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid PAYMENT_RECONCILE_AFTER %q, using %v", v, defaultReconcileAge)
+	}
+	return defaultReconcileAge
 }
 
 func SendAsyncNotification(email string, movieTitle string, promoCode string) {
@@ -26,4 +60,39 @@ func SendAsyncNotification(email string, movieTitle string, promoCode string) {
 	}()
 }
 
+func SendRefundNotification(email string, movieTitle string, amount float64) {
+	go func() {
+		body := "Your booking for '" + movieTitle + "' has been cancelled.\n"
+		if amount > 0 {
+			body += fmt.Sprintf("A refund of %.0f KZT has been sent to your card.\n", amount)
+		}
+		err := SendEmail(email, "CinemaGo: Booking cancelled", body)
+		if err != nil {
+			log.Println("[EMAIL] send failed:", err)
+		} else {
+			log.Println("[EMAIL] sent to:", email)
+		}
+	}()
+}
+
+func SendSessionChangeNotification(email, movieTitle string, oldStart, newStart time.Time, cinema, hall string) {
+	sendAsync(email, "CinemaGo: Your session has changed",
+		"The screening of '"+movieTitle+"' you booked has been changed.\n"+
+			"Was: "+oldStart.Format("02.01.2006 15:04")+"\n"+
+			"Now: "+newStart.Format("02.01.2006 15:04")+", "+cinema+", "+hall+"\n"+
+			"Your tickets stay valid. If the new time doesn't suit you, you can exchange them for another session of the same movie "+
+			"or cancel for a full refund from your profile:\n"+appBaseURL()+"/pages/profile.html")
+}
+
+func SendSessionCancelledNotification(email, movieTitle string, start time.Time, reason string, amount float64) {
+	body := "Unfortunately the screening of '" + movieTitle + "' on " + start.Format("02.01.2006 15:04") + " has been cancelled.\n"
+	if reason != "" {
+		body += "Reason: " + reason + "\n"
+	}
+	if amount > 0 {
+		body += fmt.Sprintf("A refund of %.0f KZT has been sent to your card.\n", amount)
+	}
+	sendAsync(email, "CinemaGo: Session cancelled", body+"We apologise for the inconvenience.")
+}
+
 func ValidateBooking(email string) bool { return email != "" }
@@ -3,12 +3,17 @@ package service
 import (
 	"bytes"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,13 +25,65 @@ type EpayAuth struct {
 	TokenType    string `json:"token_type"`
 }
 
+func EpayLocal() bool {
+	return os.Getenv("EPAY_ENV") == "local"
+}
+
+func appBaseURL() string {
+	if v := os.Getenv("APP_BASE_URL"); v != "" {
+		return v
+	}
+	return "http://localhost:8080"
+}
+
 func epayOAuthURL() string {
-	if os.Getenv("EPAY_ENV") == "prod" {
+	switch os.Getenv("EPAY_ENV") {
+	case "prod":
 		return "https://epay-oauth.homebank.kz/oauth2/token"
+	case "local":
+		return appBaseURL() + EpaySandboxPrefix + "/oauth2/token"
 	}
 	return "https://testoauth.homebank.kz/epay2/oauth2/token"
 }
 
+func epayAPIURL() string {
+	switch os.Getenv("EPAY_ENV") {
+	case "prod":
+		return "https://epay-api.homebank.kz"
+	case "local":
+		return appBaseURL() + EpaySandboxPrefix + "/api"
+	}
+	return "https://testepay.homebank.kz/api"
+}
+
+func EpayWidgetScriptURL() string {
+	switch os.Getenv("EPAY_ENV") {
+	case "prod":
+		return "https://epay.homebank.kz/payform/payment-api.js"
+	case "local":
+		return appBaseURL() + EpaySandboxPrefix + "/payment-api.js"
+	}
+	return "https://test-epay.epayment.kz/payform/payment-api.js"
+}
+
+func epayCredentials() (clientID, clientSecret, terminal string) {
+	clientID = os.Getenv("EPAY_CLIENT_ID")
+	clientSecret = os.Getenv("EPAY_CLIENT_SECRET")
+	terminal = os.Getenv("EPAY_TERMINAL_ID")
+	if EpayLocal() {
+		if clientID == "" {
+			clientID = "sandbox"
+		}
+		if clientSecret == "" {
+			clientSecret = "sandbox"
+		}
+		if terminal == "" {
+			terminal = "sandbox-terminal"
+		}
+	}
+	return clientID, clientSecret, terminal
+}
+
 func RandomSecretHash() (string, error) {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
@@ -36,9 +93,7 @@ func RandomSecretHash() (string, error) {
 }
 
 func GetEpayToken(invoiceID string, amount float64, currency string, secretHash string) (*EpayAuth, error) {
-	clientID := os.Getenv("EPAY_CLIENT_ID")
-	clientSecret := os.Getenv("EPAY_CLIENT_SECRET")
-	terminal := os.Getenv("EPAY_TERMINAL_ID")
+	clientID, clientSecret, terminal := epayCredentials()
 	if clientID == "" || clientSecret == "" || terminal == "" {
 		return nil, fmt.Errorf("missing EPAY env vars: EPAY_CLIENT_ID/EPAY_CLIENT_SECRET/EPAY_TERMINAL_ID")
 	}
@@ -77,6 +132,69 @@ func GetEpayToken(invoiceID string, amount float64, currency string, secretHash
 	return &auth, nil
 }
 
+func getEpayServiceToken() (*EpayAuth, error) {
+	clientID, clientSecret, _ := epayCredentials()
+	if clientID == "" || clientSecret == "" {
+		return nil, fmt.Errorf("missing EPAY env vars: EPAY_CLIENT_ID/EPAY_CLIENT_SECRET")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("scope", "webapi usermanagement email_send verification statement statistics payment")
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+
+	req, _ := http.NewRequest("POST", epayOAuthURL(), bytes.NewBufferString(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	httpClient := &http.Client{Timeout: 20 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var b bytes.Buffer
+		_, _ = b.ReadFrom(resp.Body)
+		return nil, fmt.Errorf("epay oauth error: %s: %s", resp.Status, b.String())
+	}
+
+	var auth EpayAuth
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
+
+func RefundEpayPayment(epayID string, amount float64) error {
+	if epayID == "" {
+		return fmt.Errorf("payment has no epay operation id")
+	}
+	auth, err := getEpayServiceToken()
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/operation/%s/refund?amount=%.0f", epayAPIURL(), url.PathEscape(epayID), amount)
+	req, _ := http.NewRequest("POST", endpoint, nil)
+	req.Header.Set("Authorization", "Bearer "+auth.AccessToken)
+
+	httpClient := &http.Client{Timeout: 20 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var b bytes.Buffer
+		_, _ = b.ReadFrom(resp.Body)
+		return fmt.Errorf("epay refund error: %s: %s", resp.Status, b.String())
+	}
+	return nil
+}
+
 type EpayWidgetPaymentObject struct {
 	InvoiceId       string    `json:"invoiceId"`
 	InvoiceIdAlt    string    `json:"invoiceIdAlt,omitempty"`
@@ -96,11 +214,8 @@ type EpayWidgetPaymentObject struct {
 }
 
 func BuildWidgetPaymentObject(auth *EpayAuth, invoiceID string, amount float64, currency string) (*EpayWidgetPaymentObject, error) {
-	baseURL := os.Getenv("APP_BASE_URL")
-	terminal := os.Getenv("EPAY_TERMINAL_ID")
-	if baseURL == "" {
-		baseURL = "http://localhost:8080"
-	}
+	baseURL := appBaseURL()
+	_, _, terminal := epayCredentials()
 
 	return &EpayWidgetPaymentObject{
 		InvoiceId:       invoiceID,
@@ -117,3 +232,166 @@ func BuildWidgetPaymentObject(auth *EpayAuth, invoiceID string, amount float64,
 		Auth:            auth,
 	}, nil
 }
+
+var (
+	ErrCallbackSignature = errors.New("callback secret hash mismatch")
+	ErrCallbackAmount    = errors.New("callback amount mismatch")
+)
+
+func callbackAmount(v any) (float64, bool) {
+	switch a := v.(type) {
+	case float64:
+		return a, true
+	case string:
+		f, err := strconv.ParseFloat(a, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func VerifyEpayCallback(cb map[string]any, secretHash string, amount float64, requireAmount bool) error {
+	got, _ := cb["secret_hash"].(string)
+	if secretHash == "" || subtle.ConstantTimeCompare([]byte(got), []byte(secretHash)) != 1 {
+		return ErrCallbackSignature
+	}
+	paid, ok := callbackAmount(cb["amount"])
+	if !ok {
+		if requireAmount {
+			return ErrCallbackAmount
+		}
+		return nil
+	}
+	if math.Abs(paid-amount) > 0.01 {
+		return ErrCallbackAmount
+	}
+	return nil
+}
+
+type EpayTransaction struct {
+	ID         string  `json:"id"`
+	InvoiceID  string  `json:"invoiceID"`
+	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency"`
+	StatusName string  `json:"statusName"`
+}
+
+type EpayStatusResponse struct {
+	ResultCode    string          `json:"resultCode"`
+	ResultMessage string          `json:"resultMessage"`
+	Transaction   EpayTransaction `json:"transaction"`
+}
+
+func GetEpayPaymentStatus(invoiceID string) (*EpayStatusResponse, error) {
+	auth, err := getEpayServiceToken()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := fmt.Sprintf("%s/check-status/payment/transaction/%s", epayAPIURL(), url.PathEscape(invoiceID))
+	req, _ := http.NewRequest("GET", endpoint, nil)
+	req.Header.Set("Authorization", "Bearer "+auth.AccessToken)
+
+	httpClient := &http.Client{Timeout: 20 * time.Second}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var b bytes.Buffer
+		_, _ = b.ReadFrom(resp.Body)
+		return nil, fmt.Errorf("epay status error: %s: %s", resp.Status, b.String())
+	}
+
+	var status EpayStatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+type epayProvider struct{}
+
+func (epayProvider) Name() string { return "epay" }
+
+func (epayProvider) Init(in PaymentInit) (*PaymentSession, error) {
+	secretHash, err := RandomSecretHash()
+	if err != nil {
+		return nil, err
+	}
+	auth, err := GetEpayToken(in.InvoiceID, in.Amount, in.Currency, secretHash)
+	if err != nil {
+		return nil, err
+	}
+	paymentObj, err := BuildWidgetPaymentObject(auth, in.InvoiceID, in.Amount, in.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if in.Description != "" {
+		paymentObj.Description = in.Description
+	}
+	return &PaymentSession{
+		Provider:   "epay",
+		InvoiceID:  in.InvoiceID,
+		Terminal:   paymentObj.Terminal,
+		SecretHash: secretHash,
+		Client: map[string]any{
+			"auth":        auth,
+			"payment_obj": paymentObj,
+			"widget_url":  EpayWidgetScriptURL(),
+		},
+	}, nil
+}
+
+func (epayProvider) ParseCallback(body []byte, failure bool) (PaymentCallback, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return PaymentCallback{}, fmt.Errorf("invalid callback body")
+	}
+	invoiceID, _ := raw["invoiceId"].(string)
+	code, _ := raw["code"].(string)
+	ref, _ := raw["id"].(string)
+	if invoiceID == "" {
+		return PaymentCallback{}, fmt.Errorf("missing invoiceId")
+	}
+	return PaymentCallback{
+		InvoiceID:   invoiceID,
+		ProviderRef: ref,
+		Success:     !failure && code == "ok",
+		Raw:         raw,
+	}, nil
+}
+
+func (epayProvider) VerifyCallback(cb PaymentCallback, secretHash string, amount float64) error {
+	return VerifyEpayCallback(cb.Raw, secretHash, amount, cb.Success)
+}
+
+func (epayProvider) Status(invoiceID string) (ProviderPaymentStatus, error) {
+	res, err := GetEpayPaymentStatus(invoiceID)
+	if err != nil {
+		return ProviderPaymentStatus{}, err
+	}
+	out := ProviderPaymentStatus{
+		Status:      ProviderStatusPending,
+		ProviderRef: res.Transaction.ID,
+		Amount:      res.Transaction.Amount,
+		Raw:         res,
+	}
+	if res.ResultCode != "100" {
+		return out, nil
+	}
+	switch strings.ToUpper(res.Transaction.StatusName) {
+	case "CHARGE", "AUTH":
+		out.Status = ProviderStatusPaid
+	case "REFUND", "CANCEL":
+		out.Status = ProviderStatusRefunded
+	case "REJECT", "FAILED", "CANCEL_OLD":
+		out.Status = ProviderStatusFailed
+	}
+	return out, nil
+}
+
+func (epayProvider) Refund(providerRef string, amount float64, currency string) error {
+	return RefundEpayPayment(providerRef, amount)
+}
@@ -0,0 +1,400 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const EpaySandboxPrefix = "/epay-sandbox"
+
+const (
+	SandboxApprove   = "approve"
+	SandboxDecline   = "decline"
+	SandboxDelayed   = "delayed"
+	SandboxDuplicate = "duplicate"
+)
+
+const defaultSandboxDelay = 5 * time.Second
+
+var ErrSandboxInvoiceNotFound = errors.New("sandbox invoice not found")
+
+type sandboxInvoice struct {
+	InvoiceID       string
+	Amount          float64
+	Currency        string
+	Terminal        string
+	SecretHash      string
+	Token           string
+	Description     string
+	BackLink        string
+	FailureBackLink string
+	PostLink        string
+	FailurePostLink string
+	OperationID     string
+	Status          string
+}
+
+type EpaySandbox struct {
+	mu       sync.Mutex
+	invoices map[string]*sandboxInvoice
+	tokens   map[string]bool
+	client   *http.Client
+	delay    time.Duration
+}
+
+func NewEpaySandbox() *EpaySandbox {
+	delay := defaultSandboxDelay
+	if v := os.Getenv("EPAY_SANDBOX_DELAY"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			delay = d
+		} else {
+			log.Printf("Invalid EPAY_SANDBOX_DELAY %q, using %v", v, defaultSandboxDelay)
+		}
+	}
+	return &EpaySandbox{
+		invoices: make(map[string]*sandboxInvoice),
+		tokens:   make(map[string]bool),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		delay:    delay,
+	}
+}
+
+func (s *EpaySandbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, EpaySandboxPrefix)
+	switch {
+	case path == "/oauth2/token":
+		s.tokenHandler(w, r)
+	case path == "/payment-api.js":
+		s.scriptHandler(w, r)
+	case path == "/invoices":
+		s.invoiceHandler(w, r)
+	case path == "/pay":
+		s.payHandler(w, r)
+	case strings.HasPrefix(path, "/api/check-status/payment/transaction/"):
+		s.statusHandler(w, r, strings.TrimPrefix(path, "/api/check-status/payment/transaction/"))
+	case strings.HasPrefix(path, "/api/operation/") && strings.HasSuffix(path, "/refund"):
+		s.refundHandler(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/api/operation/"), "/refund"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func sandboxJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+func (s *EpaySandbox) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sandboxJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		sandboxJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid form"})
+		return
+	}
+	if r.PostForm.Get("client_id") == "" || r.PostForm.Get("client_secret") == "" {
+		sandboxJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid client"})
+		return
+	}
+
+	token, err := RandomSecretHash()
+	if err != nil {
+		sandboxJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	s.tokens[token] = true
+	if invoiceID := r.PostForm.Get("invoiceID"); invoiceID != "" {
+		amount, _ := strconv.ParseFloat(r.PostForm.Get("amount"), 64)
+		s.invoices[invoiceID] = &sandboxInvoice{
+			InvoiceID:  invoiceID,
+			Amount:     amount,
+			Currency:   r.PostForm.Get("curency"),
+			Terminal:   r.PostForm.Get("terminal"),
+			SecretHash: r.PostForm.Get("secret_hash"),
+			Token:      token,
+			Status:     "new",
+		}
+	}
+	s.mu.Unlock()
+
+	sandboxJSON(w, http.StatusOK, EpayAuth{
+		AccessToken: token,
+		ExpiresIn:   "7200",
+		Scope:       r.PostForm.Get("scope"),
+		TokenType:   "Bearer",
+	})
+}
+
+const sandboxScript = `(function () {
+  var base = %q;
+  window.halyk = {
+    showPaymentWidget: function (obj, cb) {
+      fetch(base + "/invoices", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(obj)
+      }).then(function (res) {
+        if (!res.ok) throw new Error("sandbox rejected invoice: " + res.status);
+        window.location.href = base + "/pay?invoiceId=" + encodeURIComponent(obj.invoiceId);
+      }).catch(function (e) {
+        if (cb) cb({ success: false, error: e.message });
+      });
+    }
+  };
+  window.halyk.pay = window.halyk.showPaymentWidget;
+})();
+`
+
+func (s *EpaySandbox) scriptHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript")
+	_, _ = fmt.Fprintf(w, sandboxScript, appBaseURL()+EpaySandboxPrefix)
+}
+
+func (s *EpaySandbox) invoiceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sandboxJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
+		return
+	}
+	var obj EpayWidgetPaymentObject
+	if err := json.NewDecoder(r.Body).Decode(&obj); err != nil {
+		sandboxJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid payment object"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, ok := s.invoices[obj.InvoiceId]
+	if !ok {
+		sandboxJSON(w, http.StatusNotFound, map[string]string{"error": ErrSandboxInvoiceNotFound.Error()})
+		return
+	}
+	if obj.Auth == nil || obj.Auth.AccessToken != inv.Token {
+		sandboxJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid auth token"})
+		return
+	}
+	inv.Description = obj.Description
+	inv.BackLink = obj.BackLink
+	inv.FailureBackLink = obj.FailureBackLink
+	inv.PostLink = obj.PostLink
+	inv.FailurePostLink = obj.FailurePostLink
+	sandboxJSON(w, http.StatusOK, map[string]string{"status": "registered"})
+}
+
+var sandboxPage = template.Must(template.New("pay").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8" />
+  <title>ePay sandbox</title>
+  <style>
+    body { font-family: system-ui, Arial; padding: 24px; }
+    .card { max-width: 480px; margin: 0 auto; padding: 20px; border: 1px solid #eee; border-radius: 16px; }
+    button { padding: 10px 14px; margin: 4px 4px 0 0; border-radius: 10px; border: 0; cursor: pointer; }
+  </style>
+</head>
+<body>
+  <div class="card">
+    <h2>ePay sandbox</h2>
+    <p>{{.Description}}</p>
+    <p>Invoice <b>{{.InvoiceID}}</b>: {{printf "%.0f" .Amount}} {{.Currency}}</p>
+    <form method="POST">
+      <input type="hidden" name="invoiceId" value="{{.InvoiceID}}" />
+      <button name="outcome" value="approve">Approve</button>
+      <button name="outcome" value="decline">Decline</button>
+      <button name="outcome" value="delayed">Approve (delayed callback)</button>
+      <button name="outcome" value="duplicate">Approve (duplicate callback)</button>
+    </form>
+  </div>
+</body>
+</html>
+`))
+
+func (s *EpaySandbox) payHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		inv, ok := s.invoices[r.URL.Query().Get("invoiceId")]
+		var view sandboxInvoice
+		if ok {
+			view = *inv
+		}
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, ErrSandboxInvoiceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = sandboxPage.Execute(w, view)
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form", http.StatusBadRequest)
+			return
+		}
+		redirect, err := s.Complete(r.Form.Get("invoiceId"), r.Form.Get("outcome"))
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrSandboxInvoiceNotFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if r.URL.Query().Get("redirect") == "false" || redirect == "" {
+			sandboxJSON(w, http.StatusOK, map[string]string{"status": "accepted"})
+			return
+		}
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+	default:
+		http.Error(w, "GET or POST only", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *EpaySandbox) Complete(invoiceID, outcome string) (string, error) {
+	s.mu.Lock()
+	inv, ok := s.invoices[invoiceID]
+	if !ok {
+		s.mu.Unlock()
+		return "", ErrSandboxInvoiceNotFound
+	}
+	if inv.Status != "new" {
+		s.mu.Unlock()
+		return "", fmt.Errorf("invoice %s already %s", invoiceID, inv.Status)
+	}
+
+	var link, redirect string
+	payload := map[string]any{
+		"invoiceId":   inv.InvoiceID,
+		"amount":      inv.Amount,
+		"currency":    inv.Currency,
+		"terminal":    inv.Terminal,
+		"secret_hash": inv.SecretHash,
+	}
+	switch outcome {
+	case SandboxApprove, SandboxDelayed, SandboxDuplicate, "":
+		opID, _ := RandomSecretHash()
+		inv.OperationID = opID
+		inv.Status = "charged"
+		payload["id"] = opID
+		payload["code"] = "ok"
+		payload["reason"] = "success"
+		link, redirect = inv.PostLink, inv.BackLink
+	case SandboxDecline:
+		inv.Status = "declined"
+		payload["code"] = "error"
+		payload["reason"] = "declined by sandbox"
+		link, redirect = inv.FailurePostLink, inv.FailureBackLink
+	default:
+		s.mu.Unlock()
+		return "", fmt.Errorf("unknown outcome %q", outcome)
+	}
+	s.mu.Unlock()
+
+	if link == "" {
+		link = appBaseURL() + "/pay/callback"
+		if outcome == SandboxDecline {
+			link = appBaseURL() + "/pay/failure"
+		}
+	}
+
+	switch outcome {
+	case SandboxDelayed:
+		go func() {
+			time.Sleep(s.delay)
+			s.postCallback(link, payload)
+		}()
+	case SandboxDuplicate:
+		s.postCallback(link, payload)
+		s.postCallback(link, payload)
+	default:
+		s.postCallback(link, payload)
+	}
+	return redirect, nil
+}
+
+func (s *EpaySandbox) postCallback(link string, payload map[string]any) {
+	body, _ := json.Marshal(payload)
+	resp, err := s.client.Post(link, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Println("[EPAY-SANDBOX] callback failed:", err)
+		return
+	}
+	defer resp.Body.Close()
+	log.Printf("[EPAY-SANDBOX] callback %s -> %s", link, resp.Status)
+}
+
+var sandboxStatusNames = map[string]string{
+	"new":      "NEW",
+	"charged":  "CHARGE",
+	"declined": "REJECT",
+	"refunded": "REFUND",
+}
+
+func (s *EpaySandbox) statusHandler(w http.ResponseWriter, r *http.Request, invoiceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !s.tokens[token] {
+		sandboxJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+	inv, ok := s.invoices[invoiceID]
+	if !ok {
+		sandboxJSON(w, http.StatusOK, EpayStatusResponse{ResultCode: "102", ResultMessage: "transaction not found"})
+		return
+	}
+	sandboxJSON(w, http.StatusOK, EpayStatusResponse{
+		ResultCode:    "100",
+		ResultMessage: "SUCCESS",
+		Transaction: EpayTransaction{
+			ID:         inv.OperationID,
+			InvoiceID:  inv.InvoiceID,
+			Amount:     inv.Amount,
+			Currency:   inv.Currency,
+			StatusName: sandboxStatusNames[inv.Status],
+		},
+	})
+}
+
+func (s *EpaySandbox) refundHandler(w http.ResponseWriter, r *http.Request, operationID string) {
+	if r.Method != http.MethodPost {
+		sandboxJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !s.tokens[token] {
+		sandboxJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+	for _, inv := range s.invoices {
+		if inv.OperationID != operationID {
+			continue
+		}
+		if inv.Status != "charged" {
+			sandboxJSON(w, http.StatusConflict, map[string]string{"error": "operation is " + inv.Status})
+			return
+		}
+		inv.Status = "refunded"
+		sandboxJSON(w, http.StatusOK, map[string]string{"status": "refunded"})
+		return
+	}
+	sandboxJSON(w, http.StatusNotFound, map[string]string{"error": "operation not found"})
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"log"
 	"os"
@@ -20,28 +23,78 @@ func InitJWT() {
 }
 
 type JWTClaim struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Role     string   `json:"role"`
+	Cinemas  []string `json:"cinemas,omitempty"`
+	Version  int      `json:"ver"`
+	MFA      bool     `json:"mfa,omitempty"`
 	jwt.StandardClaims
 }
 
-func GenerateJWT(email, username, role string) (string, error) {
-	expirationTime := time.Now().Add(1 * time.Hour)
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+func AccessTokenTTL() time.Duration {
+	if v := os.Getenv("ACCESS_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid ACCESS_TOKEN_TTL %q, using %v", v, defaultAccessTokenTTL)
+	}
+	return defaultAccessTokenTTL
+}
+
+func RefreshTokenTTL() time.Duration {
+	if v := os.Getenv("REFRESH_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid REFRESH_TOKEN_TTL %q, using %v", v, defaultRefreshTokenTTL)
+	}
+	return defaultRefreshTokenTTL
+}
+
+func GenerateJWT(email, username, role string, cinemas []string, version int, mfa bool) (string, error) {
+	now := time.Now()
+	jti, err := RandomSecretHash()
+	if err != nil {
+		return "", err
+	}
 
 	claims := &JWTClaim{
 		Email:    email,
 		Username: username,
 		Role:     role,
+		Cinemas:  cinemas,
+		Version:  version,
+		MFA:      mfa,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-			IssuedAt:  time.Now().Unix(),
+			Id:        jti,
+			ExpiresAt: now.Add(AccessTokenTTL()).Unix(),
+			IssuedAt:  now.Unix(),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtKey)
 }
 
+func NewRefreshToken() (raw string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = hex.EncodeToString(b)
+	return raw, HashRefreshToken(raw), nil
+}
+
+func HashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
 func ValidateToken(tokenString string) error {
 	t, err := jwt.ParseWithClaims(tokenString, &JWTClaim{}, func(t *jwt.Token) (interface{}, error) {
 		return jwtKey, nil
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLoginMaxAttempts      = 5
+	defaultLoginMaxAttemptsPerIP = 20
+	defaultLoginLockoutBase      = time.Minute
+	defaultLoginLockoutMax       = time.Hour
+	defaultLoginAttemptWindow    = time.Hour
+)
+
+func intEnv(name string, def int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		log.Printf("Invalid %s %q, using %d", name, v, def)
+	}
+	return def
+}
+
+func durationEnv(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid %s %q, using %v", name, v, def)
+	}
+	return def
+}
+
+func LoginMaxAttempts() int {
+	return intEnv("LOGIN_MAX_ATTEMPTS", defaultLoginMaxAttempts)
+}
+
+func LoginMaxAttemptsPerIP() int {
+	return intEnv("LOGIN_MAX_ATTEMPTS_PER_IP", defaultLoginMaxAttemptsPerIP)
+}
+
+func LoginAttemptWindow() time.Duration {
+	return durationEnv("LOGIN_ATTEMPT_WINDOW", defaultLoginAttemptWindow)
+}
+
+func LoginAttemptStore() string {
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("LOGIN_ATTEMPT_STORE"))); v == "mongo" {
+		return v
+	}
+	return "memory"
+}
+
+func LockoutDuration(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	base := durationEnv("LOGIN_LOCKOUT_BASE", defaultLoginLockoutBase)
+	max := durationEnv("LOGIN_LOCKOUT_MAX", defaultLoginLockoutMax)
+	d := base
+	for i := threshold; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func ClientIP(r *http.Request) string {
+	if os.Getenv("TRUST_PROXY") == "true" {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			ip, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(ip)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func SendLockoutNotification(email string, until time.Time) {
+	sendAsync(email, "CinemaGo: Sign-in temporarily locked",
+		"We noticed several failed sign-in attempts on your CinemaGo account.\n"+
+			"To protect it, sign-in is locked until "+until.In(DefaultLocation()).Format("02.01.2006 15:04")+".\n"+
+			"If it wasn't you, reset your password from the sign-in page:\n"+appBaseURL()+"/pages/auth.html")
+}
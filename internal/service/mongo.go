@@ -69,3 +69,51 @@ func PaymentsCollection() *mongo.Collection {
 func UsersCollection() *mongo.Collection {
 	return mustDB().Collection("users")
 }
+
+func HoldsCollection() *mongo.Collection {
+	return mustDB().Collection("seat_holds")
+}
+
+func HallsCollection() *mongo.Collection {
+	return mustDB().Collection("halls")
+}
+
+func PricingRulesCollection() *mongo.Collection {
+	return mustDB().Collection("pricing_rules")
+}
+
+func PromoCodesCollection() *mongo.Collection {
+	return mustDB().Collection("promo_codes")
+}
+
+func LedgerCollection() *mongo.Collection {
+	return mustDB().Collection("loyalty_ledger")
+}
+
+func BalancesCollection() *mongo.Collection {
+	return mustDB().Collection("loyalty_balances")
+}
+
+func ReconciliationReportsCollection() *mongo.Collection {
+	return mustDB().Collection("reconciliation_reports")
+}
+
+func RefreshTokensCollection() *mongo.Collection {
+	return mustDB().Collection("refresh_tokens")
+}
+
+func RevokedTokensCollection() *mongo.Collection {
+	return mustDB().Collection("revoked_tokens")
+}
+
+func ActionTokensCollection() *mongo.Collection {
+	return mustDB().Collection("action_tokens")
+}
+
+func LoginAttemptsCollection() *mongo.Collection {
+	return mustDB().Collection("login_attempts")
+}
+
+func CinemasCollection() *mongo.Collection {
+	return mustDB().Collection("cinemas")
+}
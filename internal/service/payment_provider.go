@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+type PaymentInit struct {
+	InvoiceID   string
+	OrderID     string
+	Amount      float64
+	Currency    string
+	Description string
+}
+
+type PaymentSession struct {
+	Provider   string
+	InvoiceID  string
+	Terminal   string
+	SecretHash string
+	Client     map[string]any
+}
+
+type PaymentCallback struct {
+	InvoiceID   string
+	ProviderRef string
+	Success     bool
+	Raw         map[string]any
+}
+
+const (
+	ProviderStatusPending  = "pending"
+	ProviderStatusPaid     = "paid"
+	ProviderStatusFailed   = "failed"
+	ProviderStatusRefunded = "refunded"
+)
+
+type ProviderPaymentStatus struct {
+	Status      string  `json:"status"`
+	ProviderRef string  `json:"provider_ref,omitempty"`
+	Amount      float64 `json:"amount,omitempty"`
+	Raw         any     `json:"raw,omitempty"`
+}
+
+type PaymentProvider interface {
+	Name() string
+	Init(in PaymentInit) (*PaymentSession, error)
+	ParseCallback(body []byte, failure bool) (PaymentCallback, error)
+	VerifyCallback(cb PaymentCallback, secretHash string, amount float64) error
+	Status(invoiceID string) (ProviderPaymentStatus, error)
+	Refund(providerRef string, amount float64, currency string) error
+}
+
+const defaultPaymentProvider = "epay"
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]PaymentProvider{}
+)
+
+func RegisterPaymentProvider(p PaymentProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+func PaymentProviderByName(name string) (PaymentProvider, error) {
+	if name == "" {
+		name = defaultPaymentProvider
+	}
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+	return p, nil
+}
+
+func PaymentProviderFor(cinema string) (PaymentProvider, error) {
+	for _, pair := range strings.Split(os.Getenv("PAYMENT_PROVIDER_BY_CINEMA"), ",") {
+		name, provider, ok := strings.Cut(pair, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(cinema)) {
+			return PaymentProviderByName(strings.TrimSpace(provider))
+		}
+	}
+	return PaymentProviderByName(os.Getenv("PAYMENT_PROVIDER"))
+}
+
+func PaymentCurrency() string {
+	if v := strings.TrimSpace(os.Getenv("PAYMENT_CURRENCY")); v != "" {
+		if len(v) == 3 {
+			return strings.ToUpper(v)
+		}
+		log.Printf("Invalid PAYMENT_CURRENCY %q, using KZT", v)
+	}
+	return "KZT"
+}
+
+func init() {
+	RegisterPaymentProvider(epayProvider{})
+}
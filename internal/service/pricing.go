@@ -0,0 +1,243 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type PricingRuleType string
+
+const (
+	RuleMatinee      PricingRuleType = "matinee"
+	RuleDayOfWeek    PricingRuleType = "day_of_week"
+	RuleSeatCategory PricingRuleType = "seat_category"
+	RuleFormat       PricingRuleType = "format"
+	RuleTariff       PricingRuleType = "tariff"
+	RuleDiscountCap  PricingRuleType = "discount_cap"
+)
+
+type PricingRule struct {
+	ID       int             `json:"id" bson:"id"`
+	Name     string          `json:"name" bson:"name"`
+	Type     PricingRuleType `json:"type" bson:"type"`
+	Priority int             `json:"priority" bson:"priority"`
+	Active   bool            `json:"active" bson:"active"`
+
+	Percent float64 `json:"percent,omitempty" bson:"percent,omitempty"`
+	Amount  float64 `json:"amount,omitempty" bson:"amount,omitempty"`
+
+	FromHour     int            `json:"from_hour,omitempty" bson:"from_hour,omitempty"`
+	ToHour       int            `json:"to_hour,omitempty" bson:"to_hour,omitempty"`
+	Days         []time.Weekday `json:"days,omitempty" bson:"days,omitempty"`
+	SeatCategory string         `json:"seat_category,omitempty" bson:"seat_category,omitempty"`
+	Format       string         `json:"format,omitempty" bson:"format,omitempty"`
+	MinAge       int            `json:"min_age,omitempty" bson:"min_age,omitempty"`
+	MaxAge       int            `json:"max_age,omitempty" bson:"max_age,omitempty"`
+	Student      bool           `json:"student,omitempty" bson:"student,omitempty"`
+}
+
+type PriceInput struct {
+	BasePrice    float64
+	StartTime    time.Time
+	SeatCategory string
+	Format       string
+	Age          int
+	IsStudent    bool
+}
+
+type PriceLine struct {
+	RuleID int             `json:"rule_id" bson:"rule_id"`
+	Name   string          `json:"name" bson:"name"`
+	Type   PricingRuleType `json:"type" bson:"type"`
+	Amount float64         `json:"amount" bson:"amount"`
+}
+
+type PriceQuote struct {
+	BasePrice  float64     `json:"base_price" bson:"base_price"`
+	FinalPrice float64     `json:"final_price" bson:"final_price"`
+	Lines      []PriceLine `json:"lines" bson:"lines"`
+}
+
+func DefaultPricingRules() []PricingRule {
+	return []PricingRule{
+		{Name: "Student discount", Type: RuleTariff, Priority: 50, Active: true, Percent: -20, Student: true},
+	}
+}
+
+func (r PricingRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	switch r.Type {
+	case RuleMatinee:
+		if r.FromHour < 0 || r.ToHour > 24 || r.FromHour >= r.ToHour {
+			return fmt.Errorf("matinee rule needs 0 <= from_hour < to_hour <= 24")
+		}
+	case RuleDayOfWeek:
+		if len(r.Days) == 0 {
+			return fmt.Errorf("day_of_week rule needs days")
+		}
+		for _, d := range r.Days {
+			if d < time.Sunday || d > time.Saturday {
+				return fmt.Errorf("invalid weekday %d", d)
+			}
+		}
+	case RuleSeatCategory:
+		if r.SeatCategory == "" {
+			return fmt.Errorf("seat_category rule needs seat_category")
+		}
+	case RuleFormat:
+		if r.Format == "" {
+			return fmt.Errorf("format rule needs format")
+		}
+	case RuleTariff:
+		if !r.Student && r.MinAge == 0 && r.MaxAge == 0 {
+			return fmt.Errorf("tariff rule needs student or an age range")
+		}
+	case RuleDiscountCap:
+		if r.Percent <= 0 || r.Percent > 100 {
+			return fmt.Errorf("discount_cap percent must be in (0, 100]")
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q", r.Type)
+	}
+	if r.Type != RuleDiscountCap && r.Percent < -100 {
+		return fmt.Errorf("percent cannot be below -100")
+	}
+	return nil
+}
+
+func (r PricingRule) matches(in PriceInput) bool {
+	switch r.Type {
+	case RuleMatinee:
+		h := in.StartTime.Hour()
+		return h >= r.FromHour && h < r.ToHour
+	case RuleDayOfWeek:
+		for _, d := range r.Days {
+			if in.StartTime.Weekday() == d {
+				return true
+			}
+		}
+		return false
+	case RuleSeatCategory:
+		return strings.EqualFold(r.SeatCategory, in.SeatCategory)
+	case RuleFormat:
+		return strings.EqualFold(r.Format, in.Format)
+	case RuleTariff:
+		if r.Student {
+			return in.IsStudent
+		}
+		if in.Age <= 0 {
+			return false
+		}
+		if r.MinAge > 0 && in.Age < r.MinAge {
+			return false
+		}
+		if r.MaxAge > 0 && in.Age > r.MaxAge {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func roundPrice(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func QuotePrice(rules []PricingRule, in PriceInput) PriceQuote {
+	ordered := make([]PricingRule, 0, len(rules))
+	for _, r := range rules {
+		if r.Active {
+			ordered = append(ordered, r)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority < ordered[j].Priority
+	})
+
+	q := PriceQuote{BasePrice: in.BasePrice, Lines: make([]PriceLine, 0)}
+	price := in.BasePrice
+	gross := in.BasePrice
+	discount := 0.0
+	var capRule *PricingRule
+
+	for i, r := range ordered {
+		if r.Type == RuleDiscountCap {
+			if capRule == nil || r.Percent < capRule.Percent {
+				capRule = &ordered[i]
+			}
+			continue
+		}
+		if !r.matches(in) {
+			continue
+		}
+		delta := roundPrice(price*r.Percent/100 + r.Amount)
+		if delta == 0 {
+			continue
+		}
+		if price+delta < 0 {
+			delta = -price
+		}
+		price += delta
+		if delta < 0 {
+			discount -= delta
+		} else {
+			gross += delta
+		}
+		q.Lines = append(q.Lines, PriceLine{RuleID: r.ID, Name: r.Name, Type: r.Type, Amount: delta})
+	}
+
+	if capRule != nil && discount > gross*capRule.Percent/100 {
+		back := roundPrice(discount - gross*capRule.Percent/100)
+		price += back
+		q.Lines = append(q.Lines, PriceLine{RuleID: capRule.ID, Name: capRule.Name, Type: capRule.Type, Amount: back})
+	}
+
+	q.FinalPrice = roundPrice(price)
+	return q
+}
+
+func DescribePricingRule(r PricingRule) string {
+	var adj []string
+	if r.Percent != 0 && r.Type != RuleDiscountCap {
+		adj = append(adj, fmt.Sprintf("%+g%%", r.Percent))
+	}
+	if r.Amount != 0 {
+		adj = append(adj, fmt.Sprintf("%+g KZT", r.Amount))
+	}
+	change := strings.Join(adj, " ")
+
+	switch r.Type {
+	case RuleMatinee:
+		return fmt.Sprintf("%s: %s for sessions starting %02d:00-%02d:00", r.Name, change, r.FromHour, r.ToHour)
+	case RuleDayOfWeek:
+		days := make([]string, 0, len(r.Days))
+		for _, d := range r.Days {
+			days = append(days, d.String())
+		}
+		return fmt.Sprintf("%s: %s on %s", r.Name, change, strings.Join(days, ", "))
+	case RuleSeatCategory:
+		return fmt.Sprintf("%s: %s for %s seats", r.Name, change, r.SeatCategory)
+	case RuleFormat:
+		return fmt.Sprintf("%s: %s for %s screenings", r.Name, change, strings.ToUpper(r.Format))
+	case RuleTariff:
+		if r.Student {
+			return fmt.Sprintf("%s: %s for students", r.Name, change)
+		}
+		switch {
+		case r.MinAge > 0 && r.MaxAge > 0:
+			return fmt.Sprintf("%s: %s for ages %d-%d", r.Name, change, r.MinAge, r.MaxAge)
+		case r.MaxAge > 0:
+			return fmt.Sprintf("%s: %s for ages up to %d", r.Name, change, r.MaxAge)
+		default:
+			return fmt.Sprintf("%s: %s for ages %d+", r.Name, change, r.MinAge)
+		}
+	case RuleDiscountCap:
+		return fmt.Sprintf("%s: combined discounts never exceed %g%%", r.Name, r.Percent)
+	}
+	return r.Name
+}
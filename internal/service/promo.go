@@ -3,9 +3,14 @@ package service
 import (
 	"crypto/rand"
 	"encoding/base32"
+	"log"
+	"os"
 	"strings"
+	"time"
 )
 
+const defaultBonusTTL = 365 * 24 * time.Hour
+
 func GeneratePromoCode() string {
 	b := make([]byte, 5)
 	_, _ = rand.Read(b)
@@ -19,3 +24,13 @@ func GeneratePromoCode() string {
 func CalcBonuses(price float64) int {
 	return int(price * 0.05)
 }
+
+func BonusTTL() time.Duration {
+	if v := os.Getenv("BONUS_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid BONUS_TTL %q, using %v", v, defaultBonusTTL)
+	}
+	return defaultBonusTTL
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	RoleUser          = "user"
+	RoleSuperAdmin    = "super_admin"
+	RoleCinemaManager = "cinema_manager"
+	RoleCashier       = "cashier"
+	RoleUsher         = "usher"
+
+	legacyRoleAdmin = "admin"
+)
+
+type Permission string
+
+const (
+	PermSessionsWrite Permission = "sessions:write"
+	PermHallsWrite    Permission = "halls:write"
+	PermCinemasWrite  Permission = "cinemas:write"
+	PermMoviesWrite   Permission = "movies:write"
+	PermOrdersRead    Permission = "orders:read"
+	PermRefundsCreate Permission = "refunds:create"
+	PermCheckinScan   Permission = "checkin:scan"
+	PermPricingWrite  Permission = "pricing:write"
+	PermPromosWrite   Permission = "promos:write"
+	PermReportsRead   Permission = "reports:read"
+	PermUsersManage   Permission = "users:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleSuperAdmin: {
+		PermSessionsWrite, PermHallsWrite, PermOrdersRead, PermRefundsCreate, PermCheckinScan,
+		PermPricingWrite, PermPromosWrite, PermReportsRead, PermUsersManage, PermMoviesWrite, PermCinemasWrite,
+	},
+	RoleCinemaManager: {PermSessionsWrite, PermHallsWrite, PermOrdersRead, PermRefundsCreate, PermCheckinScan},
+	RoleCashier:       {PermOrdersRead, PermRefundsCreate, PermCheckinScan},
+	RoleUsher:         {PermCheckinScan},
+	RoleUser:          {},
+}
+
+const CinemasKey contextKey = "cinemas"
+
+func NormalizeRole(role string) string {
+	if role == legacyRoleAdmin {
+		return RoleSuperAdmin
+	}
+	return role
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func IsScopedRole(role string) bool {
+	role = NormalizeRole(role)
+	return role != RoleSuperAdmin && role != RoleUser
+}
+
+func RoleHasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[NormalizeRole(role)] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func RolePermissions(role string) []Permission {
+	return rolePermissions[NormalizeRole(role)]
+}
+
+func HasPermission(ctx context.Context, perm Permission) bool {
+	role, _ := ctx.Value(RoleKey).(string)
+	return RoleHasPermission(role, perm)
+}
+
+func CanAccessCinema(ctx context.Context, perm Permission, cinema string) bool {
+	role, _ := ctx.Value(RoleKey).(string)
+	if !RoleHasPermission(role, perm) {
+		return false
+	}
+	if !IsScopedRole(role) {
+		return true
+	}
+	cinemas, _ := ctx.Value(CinemasKey).([]string)
+	for _, c := range cinemas {
+		if c == cinema {
+			return true
+		}
+	}
+	return false
+}
+
+func RequirePermission(perm Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasPermission(r.Context(), perm) {
+				http.Error(w, "Forbidden: missing permission "+string(perm), http.StatusForbidden)
+				return
+			}
+			if claims, _ := r.Context().Value(ClaimsKey).(*JWTClaim); claims == nil || (TwoFactorRequired(claims.Role) && !claims.MFA) {
+				http.Error(w, "Forbidden: two-factor authentication required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package service
+
+import "time"
+
+const (
+	defaultSessionCleaningBuffer = 15 * time.Minute
+	defaultSessionRuntime        = 2 * time.Hour
+	MaxScheduleDays              = 92
+	MaxScheduleSessions          = 500
+)
+
+func SessionCleaningBuffer() time.Duration {
+	return durationEnv("SESSION_CLEANING_BUFFER", defaultSessionCleaningBuffer)
+}
+
+func DefaultSessionRuntime() time.Duration {
+	return durationEnv("DEFAULT_SESSION_RUNTIME", defaultSessionRuntime)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultSeatEventsHeartbeat = 25 * time.Second
+	seatEventBuffer            = 64
+)
+
+type SeatEventType string
+
+const (
+	SeatHeld     SeatEventType = "seat_held"
+	SeatSold     SeatEventType = "seat_sold"
+	SeatReleased SeatEventType = "seat_released"
+)
+
+type SeatEvent struct {
+	Type      SeatEventType `json:"type"`
+	SessionID int           `json:"session_id"`
+	Seat      string        `json:"seat"`
+	At        time.Time     `json:"at"`
+}
+
+func SeatEventsHeartbeat() time.Duration {
+	return durationEnv("SEAT_EVENTS_HEARTBEAT", defaultSeatEventsHeartbeat)
+}
+
+type SeatEventBus struct {
+	mu   sync.Mutex
+	subs map[int]map[chan SeatEvent]struct{}
+}
+
+func NewSeatEventBus() *SeatEventBus {
+	return &SeatEventBus{subs: map[int]map[chan SeatEvent]struct{}{}}
+}
+
+var seatEvents = NewSeatEventBus()
+
+func SeatEvents() *SeatEventBus { return seatEvents }
+
+func (b *SeatEventBus) Subscribe(sessionID int) (<-chan SeatEvent, func()) {
+	ch := make(chan SeatEvent, seatEventBuffer)
+	b.mu.Lock()
+	if b.subs[sessionID] == nil {
+		b.subs[sessionID] = map[chan SeatEvent]struct{}{}
+	}
+	b.subs[sessionID][ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.drop(sessionID, ch)
+	}
+}
+
+// A subscriber that falls behind is disconnected rather than blocking the
+// publisher; the client reconnects and starts again from a fresh snapshot.
+func (b *SeatEventBus) Publish(e SeatEvent) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[e.SessionID] {
+		select {
+		case ch <- e:
+		default:
+			b.drop(e.SessionID, ch)
+		}
+	}
+}
+
+func (b *SeatEventBus) drop(sessionID int, ch chan SeatEvent) {
+	subs := b.subs[sessionID]
+	if _, ok := subs[ch]; !ok {
+		return
+	}
+	delete(subs, ch)
+	close(ch)
+	if len(subs) == 0 {
+		delete(b.subs, sessionID)
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	_ "time/tzdata"
+)
+
+const DefaultTimezone = "Asia/Almaty"
+
+var (
+	locations   sync.Map
+	defaultOnce sync.Once
+	defaultLoc  *time.Location
+)
+
+func DefaultLocation() *time.Location {
+	defaultOnce.Do(func() {
+		loc, err := time.LoadLocation(DefaultTimezone)
+		if err != nil {
+			log.Printf("Failed to load timezone %s, using UTC: %v", DefaultTimezone, err)
+			loc = time.UTC
+		}
+		defaultLoc = loc
+	})
+	return defaultLoc
+}
+
+func ParseLocation(tz string) (*time.Location, error) {
+	if tz == "" {
+		return DefaultLocation(), nil
+	}
+	if loc, ok := locations.Load(tz); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+	locations.Store(tz, loc)
+	return loc, nil
+}
+
+func LoadLocation(tz string) *time.Location {
+	loc, err := ParseLocation(tz)
+	if err != nil {
+		log.Printf("%v, using %s", err, DefaultTimezone)
+		return DefaultLocation()
+	}
+	return loc
+}
+
+func LocalTime(t time.Time, tz string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(LoadLocation(tz)).Format(time.RFC3339)
+}
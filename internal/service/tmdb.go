@@ -0,0 +1,367 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	tmdbBaseURL      = "https://api.themoviedb.org/3"
+	TMDBImageBaseURL = "https://image.tmdb.org/t/p/"
+
+	defaultMovieSyncInterval = 24 * time.Hour
+	defaultTMDBCacheTTL      = time.Hour
+	defaultTMDBRateLimit     = 40
+	tmdbCacheSize            = 512
+	tmdbMaxAttempts          = 3
+	tmdbBackoff              = 500 * time.Millisecond
+)
+
+var TMDBPosterSizes = []string{"w92", "w154", "w185", "w342", "w500", "w780", "original"}
+
+var tmdbGenres = map[int]string{
+	28: "Action", 12: "Adventure", 16: "Animation", 35: "Comedy", 80: "Crime",
+	99: "Documentary", 18: "Drama", 10751: "Family", 14: "Fantasy", 36: "History",
+	27: "Horror", 10402: "Music", 9648: "Mystery", 10749: "Romance", 878: "Science Fiction",
+	10770: "TV Movie", 53: "Thriller", 10752: "War", 37: "Western",
+}
+
+var (
+	ErrTMDBNotConfigured = errors.New("tmdb: api key is not configured")
+	ErrTMDBNotFound      = errors.New("tmdb: not found")
+	ErrTMDBUnauthorized  = errors.New("tmdb: unauthorized")
+	ErrTMDBRateLimited   = errors.New("tmdb: rate limited")
+	ErrTMDBUnavailable   = errors.New("tmdb: service unavailable")
+)
+
+type TMDBError struct {
+	Path       string
+	StatusCode int
+	Message    string
+	retryAfter time.Duration
+}
+
+func (e *TMDBError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("tmdb %s: %s", e.Path, e.Message)
+	}
+	if e.Message != "" {
+		return fmt.Sprintf("tmdb %s: %d %s", e.Path, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("tmdb %s: status %d", e.Path, e.StatusCode)
+}
+
+func (e *TMDBError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrTMDBNotFound
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusForbidden:
+		return ErrTMDBUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrTMDBRateLimited
+	case e.StatusCode == 0, e.StatusCode >= 500:
+		return ErrTMDBUnavailable
+	}
+	return nil
+}
+
+func (e *TMDBError) retryable() bool {
+	return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+type TMDBGenre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type TMDBVideo struct {
+	Name     string `json:"name"`
+	Site     string `json:"site"`
+	Key      string `json:"key"`
+	Type     string `json:"type"`
+	Official bool   `json:"official"`
+}
+
+type TMDBCast struct {
+	Name        string `json:"name"`
+	Character   string `json:"character"`
+	ProfilePath string `json:"profile_path"`
+	Order       int    `json:"order"`
+}
+
+type TMDBReleaseCountry struct {
+	Country      string `json:"iso_3166_1"`
+	ReleaseDates []struct {
+		Certification string `json:"certification"`
+	} `json:"release_dates"`
+}
+
+type TMDBMovie struct {
+	ID            int         `json:"id"`
+	Title         string      `json:"title"`
+	OriginalTitle string      `json:"original_title"`
+	Overview      string      `json:"overview"`
+	PosterPath    string      `json:"poster_path"`
+	BackdropPath  string      `json:"backdrop_path"`
+	ReleaseDate   string      `json:"release_date"`
+	VoteAverage   float64     `json:"vote_average"`
+	Popularity    float64     `json:"popularity"`
+	Adult         bool        `json:"adult"`
+	Runtime       int         `json:"runtime"`
+	Genres        []TMDBGenre `json:"genres"`
+	GenreIDs      []int       `json:"genre_ids"`
+
+	Videos struct {
+		Results []TMDBVideo `json:"results"`
+	} `json:"videos"`
+	Credits struct {
+		Cast []TMDBCast `json:"cast"`
+	} `json:"credits"`
+	ReleaseDates struct {
+		Results []TMDBReleaseCountry `json:"results"`
+	} `json:"release_dates"`
+}
+
+type TMDBSearchPage struct {
+	Page         int         `json:"page"`
+	Results      []TMDBMovie `json:"results"`
+	TotalPages   int         `json:"total_pages"`
+	TotalResults int         `json:"total_results"`
+}
+
+type TMDBConfig struct {
+	APIKey      string
+	AccessToken string
+	BaseURL     string
+	Timeout     time.Duration
+	CacheTTL    time.Duration
+	RateLimit   int
+	Transport   http.RoundTripper
+}
+
+type TMDBClient struct {
+	apiKey      string
+	accessToken string
+	baseURL     string
+	http        *http.Client
+	cache       *ttlCache
+	limiter     *rateLimiter
+	offline     bool
+}
+
+func NewTMDBClient(cfg TMDBConfig) *TMDBClient {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = tmdbBaseURL
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = defaultTMDBCacheTTL
+	}
+	if cfg.RateLimit == 0 {
+		cfg.RateLimit = defaultTMDBRateLimit
+	}
+	return &TMDBClient{
+		apiKey:      cfg.APIKey,
+		accessToken: cfg.AccessToken,
+		baseURL:     cfg.BaseURL,
+		http:        &http.Client{Timeout: cfg.Timeout, Transport: cfg.Transport},
+		cache:       newTTLCache(tmdbCacheSize, cfg.CacheTTL),
+		limiter:     newRateLimiter(cfg.RateLimit),
+	}
+}
+
+func NewOfflineTMDBClient(dir string) *TMDBClient {
+	c := NewTMDBClient(TMDBConfig{Transport: newFixtureTransport(dir), RateLimit: 1000})
+	c.offline = true
+	return c
+}
+
+var (
+	defaultTMDBOnce sync.Once
+	defaultTMDB     *TMDBClient
+)
+
+func DefaultTMDBClient() *TMDBClient {
+	defaultTMDBOnce.Do(func() {
+		if TMDBOffline() {
+			log.Println("Using offline TMDB fixtures")
+			defaultTMDB = NewOfflineTMDBClient(os.Getenv("TMDB_FIXTURES_DIR"))
+			return
+		}
+		defaultTMDB = NewTMDBClient(TMDBConfig{
+			APIKey:      os.Getenv("TMDB_API_KEY"),
+			AccessToken: os.Getenv("TMDB_ACCESS_TOKEN"),
+			CacheTTL:    durationEnv("TMDB_CACHE_TTL", defaultTMDBCacheTTL),
+			RateLimit:   intEnv("TMDB_RATE_LIMIT", defaultTMDBRateLimit),
+		})
+	})
+	return defaultTMDB
+}
+
+func (c *TMDBClient) configured() bool {
+	return c.offline || c.apiKey != "" || c.accessToken != ""
+}
+
+func (c *TMDBClient) get(path string, params url.Values, out any) error {
+	if !c.configured() {
+		return ErrTMDBNotConfigured
+	}
+	params.Set("language", "en-US")
+	cacheKey := path + "?" + params.Encode()
+	if body, ok := c.cache.Get(cacheKey); ok {
+		return json.Unmarshal(body, out)
+	}
+
+	var body []byte
+	var err error
+	for attempt := 0; attempt < tmdbMaxAttempts; attempt++ {
+		if body, err = c.do(path, params); err == nil {
+			break
+		}
+		var apiErr *TMDBError
+		if !errors.As(err, &apiErr) || !apiErr.retryable() || attempt == tmdbMaxAttempts-1 {
+			return err
+		}
+		wait := tmdbBackoff << attempt
+		if apiErr.retryAfter > wait {
+			wait = apiErr.retryAfter
+		}
+		time.Sleep(wait)
+	}
+
+	c.cache.Set(cacheKey, body)
+	return json.Unmarshal(body, out)
+}
+
+func (c *TMDBClient) do(path string, params url.Values) ([]byte, error) {
+	c.limiter.Wait()
+
+	query := params
+	if c.accessToken == "" && c.apiKey != "" {
+		query = url.Values{}
+		for k, v := range params {
+			query[k] = v
+		}
+		query.Set("api_key", c.apiKey)
+	}
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("tmdb %s: %w", path, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, &TMDBError{Path: path, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("tmdb %s: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &TMDBError{Path: path, StatusCode: resp.StatusCode}
+		var payload struct {
+			StatusMessage string `json:"status_message"`
+		}
+		if json.Unmarshal(body, &payload) == nil {
+			apiErr.Message = payload.StatusMessage
+		}
+		if after, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.retryAfter = time.Duration(after) * time.Second
+		}
+		return nil, apiErr
+	}
+	return body, nil
+}
+
+func (c *TMDBClient) Movie(id int) (*TMDBMovie, error) {
+	var m TMDBMovie
+	params := url.Values{"append_to_response": {"videos,credits,release_dates"}}
+	if err := c.get(fmt.Sprintf("/movie/%d", id), params, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (c *TMDBClient) SearchMovies(query string, page int) (*TMDBSearchPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	var result TMDBSearchPage
+	params := url.Values{"query": {query}, "page": {strconv.Itoa(page)}}
+	if err := c.get("/search/movie", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func FetchTMDBMovie(id int) (*TMDBMovie, error) {
+	return DefaultTMDBClient().Movie(id)
+}
+
+func SearchTMDBMovies(query string) ([]TMDBMovie, error) {
+	page, err := DefaultTMDBClient().SearchMovies(query, 1)
+	if err != nil {
+		return nil, err
+	}
+	return page.Results, nil
+}
+
+func (m TMDBMovie) Certification() string {
+	for _, country := range []string{"KZ", "RU", "US"} {
+		for _, r := range m.ReleaseDates.Results {
+			if r.Country != country {
+				continue
+			}
+			for _, d := range r.ReleaseDates {
+				if d.Certification != "" {
+					return d.Certification
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func TMDBGenreName(id int) string {
+	return tmdbGenres[id]
+}
+
+func TMDBImageURL(size, path string) string {
+	if path == "" {
+		return ""
+	}
+	return TMDBImageBaseURL + size + path
+}
+
+func TMDBOffline() bool {
+	return os.Getenv("TMDB_OFFLINE") == "true"
+}
+
+func TMDBEnabled() bool {
+	return TMDBOffline() || os.Getenv("TMDB_API_KEY") != "" || os.Getenv("TMDB_ACCESS_TOKEN") != ""
+}
+
+func MovieSyncInterval() time.Duration {
+	return durationEnv("MOVIE_SYNC_INTERVAL", defaultMovieSyncInterval)
+}
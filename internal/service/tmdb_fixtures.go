@@ -0,0 +1,89 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//go:embed fixtures/tmdb
+var embeddedTMDBFixtures embed.FS
+
+type fixtureTransport struct {
+	files fs.FS
+}
+
+func newFixtureTransport(dir string) *fixtureTransport {
+	if dir != "" {
+		return &fixtureTransport{files: os.DirFS(dir)}
+	}
+	sub, _ := fs.Sub(embeddedTMDBFixtures, "fixtures/tmdb")
+	return &fixtureTransport{files: sub}
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := strings.TrimPrefix(req.URL.Path, "/3")
+	switch {
+	case strings.HasPrefix(p, "/movie/"):
+		body, err := fs.ReadFile(t.files, "movie/"+path.Base(p)+".json")
+		if err != nil {
+			return fixtureResponse(req, http.StatusNotFound, map[string]any{"status_code": 34, "status_message": "The resource you requested could not be found."}), nil
+		}
+		return fixtureResponse(req, http.StatusOK, json.RawMessage(body)), nil
+	case p == "/search/movie":
+		return fixtureResponse(req, http.StatusOK, t.search(req.URL.Query().Get("query"), req.URL.Query().Get("page"))), nil
+	}
+	return fixtureResponse(req, http.StatusNotFound, map[string]any{"status_message": "no fixture for " + p}), nil
+}
+
+func (t *fixtureTransport) search(query, pageStr string) TMDBSearchPage {
+	const perPage = 20
+	query = strings.ToLower(strings.TrimSpace(query))
+	results := []TMDBMovie{}
+	entries, _ := fs.ReadDir(t.files, "movie")
+	for _, e := range entries {
+		body, err := fs.ReadFile(t.files, "movie/"+e.Name())
+		if err != nil {
+			continue
+		}
+		var m TMDBMovie
+		if json.Unmarshal(body, &m) != nil {
+			continue
+		}
+		if query == "" || strings.Contains(strings.ToLower(m.Title), query) || strings.Contains(strings.ToLower(m.OriginalTitle), query) {
+			for _, g := range m.Genres {
+				m.GenreIDs = append(m.GenreIDs, g.ID)
+			}
+			m.Genres, m.Videos.Results, m.Credits.Cast, m.ReleaseDates.Results = nil, nil, nil, nil
+			results = append(results, m)
+		}
+	}
+
+	page, _ := strconv.Atoi(pageStr)
+	if page < 1 {
+		page = 1
+	}
+	out := TMDBSearchPage{Page: page, Results: []TMDBMovie{}, TotalResults: len(results), TotalPages: (len(results) + perPage - 1) / perPage}
+	if start := (page - 1) * perPage; start < len(results) {
+		out.Results = results[start:min(start+perPage, len(results))]
+	}
+	return out
+}
+
+func fixtureResponse(req *http.Request, status int, payload any) *http.Response {
+	body, _ := json.Marshal(payload)
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}
+}
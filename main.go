@@ -5,6 +5,7 @@ import (
 	"cinema/internal/models"
 	"cinema/internal/service"
 	"encoding/json"
+	"errors"
 
 	"fmt"
 	"io"
@@ -20,6 +21,10 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type app struct {
+	models.Repositories
+}
+
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -35,8 +40,41 @@ func main() {
 
 	service.InitJWT()
 
-	if err := service.ConnectMongo(); err != nil {
-		log.Fatal("Mongo connection failed: ", err)
+	var repos models.Repositories
+	if os.Getenv("STORAGE") == "memory" {
+		log.Println("Using in-memory storage")
+		repos = models.NewMemoryRepositories()
+	} else {
+		if err := service.ConnectMongo(); err != nil {
+			log.Fatal("Mongo connection failed: ", err)
+		}
+		repos = models.NewMongoRepositories()
+		if err := models.EnsureIndexes(); err != nil {
+			log.Fatal("Mongo index setup failed: ", err)
+		}
+		models.StartSeatHoldStream()
+	}
+	a := &app{Repositories: repos}
+	h := api.New(repos)
+	service.SetTokenChecker(models.NewTokenChecker(repos))
+
+	if err := models.SeedPricingRules(repos.Pricing); err != nil {
+		log.Println("Pricing rules seed failed:", err)
+	}
+	if err := models.SeedCinemas(repos.Cinemas); err != nil {
+		log.Println("Cinemas seed failed:", err)
+	}
+	if n, err := models.BackfillOrderBonuses(repos); err != nil {
+		log.Fatal("Bonus backfill failed: ", err)
+	} else if n > 0 {
+		log.Printf("[BONUS] credited %d paid orders from before the ledger", n)
+	}
+	models.StartHoldSweeper(repos, 30*time.Second)
+	models.StartBonusExpirySweeper(repos, time.Hour)
+	models.StartPaymentReconciler(repos, time.Minute)
+	models.StartDailyReconciliationReport(repos, time.Hour)
+	if service.TMDBEnabled() {
+		models.StartMovieSync(repos, service.MovieSyncInterval())
 	}
 
 	port := os.Getenv("PORT")
@@ -57,27 +95,63 @@ func main() {
 		}
 		http.ServeFile(w, r, "./static/index.html")
 	})
-	mux.Handle("/user/tickets", service.AuthMiddleware(http.HandlerFunc(getUserTicketsHandler)))
+	mux.Handle("/user/tickets", service.AuthMiddleware(http.HandlerFunc(a.getUserTicketsHandler)))
 
-	mux.HandleFunc("/movies", getMovieHandler)
-	mux.HandleFunc("/login", api.LoginHandler)
-	mux.HandleFunc("/register", api.RegisterHandler)
+	mux.HandleFunc("/movies", a.moviesHandler)
+	mux.HandleFunc("/movies/search", a.searchMoviesHandler)
+	mux.Handle("/movies/import", service.AuthMiddleware(service.RequirePermission(service.PermMoviesWrite)(http.HandlerFunc(a.importMovieHandler))))
+	mux.Handle("/movies/sync", service.AuthMiddleware(service.RequirePermission(service.PermMoviesWrite)(http.HandlerFunc(a.syncMoviesHandler))))
+	mux.HandleFunc("/login", h.LoginHandler)
+	mux.HandleFunc("/register", h.RegisterHandler)
+	mux.HandleFunc("/auth/refresh", h.RefreshHandler)
+	mux.HandleFunc("/auth/verify-email", h.VerifyEmailHandler)
+	mux.HandleFunc("/auth/verify-email/resend", h.ResendVerificationHandler)
+	mux.HandleFunc("/auth/password/forgot", h.ForgotPasswordHandler)
+	mux.HandleFunc("/auth/password/reset", h.ResetPasswordHandler)
+	mux.HandleFunc("/auth/2fa/verify", h.TwoFactorVerifyHandler)
+	mux.Handle("/auth/2fa/setup", service.AuthMiddleware(http.HandlerFunc(h.TwoFactorSetupHandler)))
+	mux.Handle("/auth/2fa/enable", service.AuthMiddleware(http.HandlerFunc(h.TwoFactorEnableHandler)))
+	mux.Handle("/auth/2fa/disable", service.AuthMiddleware(http.HandlerFunc(h.TwoFactorDisableHandler)))
+	mux.Handle("/auth/2fa/recovery-codes", service.AuthMiddleware(http.HandlerFunc(h.RecoveryCodesHandler)))
+	mux.Handle("/logout", service.AuthMiddleware(http.HandlerFunc(h.LogoutHandler)))
+	mux.Handle("/logout/all", service.AuthMiddleware(http.HandlerFunc(h.LogoutAllHandler)))
 
-	mux.HandleFunc("/sessions", sessionsHandler)
+	mux.HandleFunc("/sessions", a.sessionsHandler)
 
-	mux.Handle("/book", service.AuthMiddleware(http.HandlerFunc(createBookingHandler)))
-	mux.Handle("/reserve", service.AuthMiddleware(http.HandlerFunc(reserveSeatHandler)))
-	mux.Handle("/orders", service.AuthMiddleware(service.AdminMiddleware(http.HandlerFunc(listOrdersHandler))))
+	mux.Handle("/book", service.AuthMiddleware(http.HandlerFunc(a.createBookingHandler)))
+	mux.Handle("/reserve", service.AuthMiddleware(http.HandlerFunc(a.reserveSeatHandler)))
+	mux.Handle("/orders", service.AuthMiddleware(service.RequirePermission(service.PermOrdersRead)(http.HandlerFunc(a.listOrdersHandler))))
+	mux.Handle("/orders/", service.AuthMiddleware(http.HandlerFunc(a.orderItemHandler)))
 
-	mux.Handle("/sessions/", service.AuthMiddleware(service.AdminMiddleware(http.HandlerFunc(deleteSessionHandler))))
-	mux.Handle("/user/profile", service.AuthMiddleware(http.HandlerFunc(getUserProfileHandler)))
+	mux.HandleFunc("/sessions/", a.sessionItemHandler)
+	mux.Handle("/sessions/schedule", service.AuthMiddleware(service.RequirePermission(service.PermSessionsWrite)(http.HandlerFunc(a.scheduleSessionsHandler))))
+	mux.HandleFunc("/halls", a.hallsHandler)
+	mux.HandleFunc("/cinemas", a.cinemasHandler)
+	mux.HandleFunc("/cinemas/", a.cinemaItemHandler)
+	mux.HandleFunc("/pricing/rules", a.pricingRulesHandler)
+	mux.Handle("/promos", service.AuthMiddleware(service.RequirePermission(service.PermPromosWrite)(http.HandlerFunc(a.promosHandler))))
+	mux.Handle("/promos/", service.AuthMiddleware(service.RequirePermission(service.PermPromosWrite)(http.HandlerFunc(a.promoHandler))))
+	mux.Handle("/pricing/rules/", service.AuthMiddleware(service.RequirePermission(service.PermPricingWrite)(http.HandlerFunc(a.pricingRuleHandler))))
+	mux.Handle("/user/profile", service.AuthMiddleware(http.HandlerFunc(a.getUserProfileHandler)))
+
+	mux.Handle("/pay/init", service.AuthMiddleware(http.HandlerFunc(a.payInitHandler)))
+	mux.HandleFunc("/pay/callback", a.payCallbackHandler)
+	mux.HandleFunc("/pay/failure", a.payFailureHandler)
+	mux.HandleFunc("/pay/callback/", a.payCallbackHandler)
+	mux.HandleFunc("/pay/failure/", a.payFailureHandler)
+	mux.Handle("/pay/status", service.AuthMiddleware(http.HandlerFunc(a.payStatusHandler)))
+	mux.Handle("/reconciliation/reports", service.AuthMiddleware(service.RequirePermission(service.PermReportsRead)(http.HandlerFunc(a.reconciliationReportsHandler))))
+	if service.EpayLocal() {
+		log.Println("Using local ePay sandbox at", service.EpaySandboxPrefix)
+		mux.Handle(service.EpaySandboxPrefix+"/", service.NewEpaySandbox())
+	}
 
-	mux.HandleFunc("/pay/init", payInitHandler)
-	mux.HandleFunc("/pay/callback", payCallbackHandler)
-	mux.HandleFunc("/pay/failure", payFailureHandler)
-	mux.HandleFunc("/pay/status", payStatusHandler)
+	mux.Handle("/admin/users", service.AuthMiddleware(service.RequirePermission(service.PermUsersManage)(http.HandlerFunc(h.UsersHandler))))
+	mux.Handle("/admin/users/role", service.AuthMiddleware(service.RequirePermission(service.PermUsersManage)(http.HandlerFunc(h.AssignRoleHandler))))
+	mux.Handle("/admin/users/unlock", service.AuthMiddleware(service.RequirePermission(service.PermUsersManage)(http.HandlerFunc(h.UnlockUserHandler))))
+	mux.Handle("/admin/roles", service.AuthMiddleware(service.RequirePermission(service.PermUsersManage)(http.HandlerFunc(h.RolesHandler))))
 
-	mux.HandleFunc("/ai/chat", api.AIChatHandler)
+	mux.HandleFunc("/ai/chat", h.AIChatHandler)
 
 	fmt.Printf("🎬 CinemaGo Server running at http://localhost:%s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, loggingMiddleware(mux)))
@@ -107,89 +181,472 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
-func getMovieHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Query().Get("id")
-	title := r.URL.Query().Get("title")
-	var movie *models.Movie
-	var err error
-	if title != "" {
-		movie, err = api.SearchMovieByName(title)
-	} else {
-		if id == "" {
-			id = "157336"
+func (a *app) moviesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET only"})
+		return
+	}
+
+	if title := r.URL.Query().Get("title"); title != "" {
+		page, err := models.SearchMovies(a.Repositories, models.MovieSearchQuery{Query: title, PerPage: 1})
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if len(page.Results) == 0 {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Movie not found"})
+			return
 		}
-		movie, err = api.FetchMovieDetails(id)
+		writeJSON(w, http.StatusOK, page.Results[0].Movie)
+		return
+	}
+
+	idStr := r.URL.Query().Get("id")
+	if idStr == "" {
+		movies, err := a.Movies.List()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, movies)
+		return
 	}
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return
+	}
+	movie, ok, err := a.Movies.GetByID(id)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Movie not found"})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
+	if !ok {
+		t, err := service.FetchTMDBMovie(id)
+		if errors.Is(err, service.ErrTMDBNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Movie not found"})
+			return
+		}
+		if err != nil {
+			writeJSON(w, tmdbErrorStatus(err), map[string]string{"error": err.Error()})
+			return
+		}
+		movie = models.MovieFromTMDB(*t)
+	}
 	writeJSON(w, http.StatusOK, movie)
 }
 
-func createBookingHandler(w http.ResponseWriter, r *http.Request) {
+func (a *app) searchMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET only"})
+		return
+	}
+	q := r.URL.Query()
+	query := models.MovieSearchQuery{
+		Query:      q.Get("q"),
+		Genre:      strings.TrimSpace(q.Get("genre")),
+		NowShowing: q.Get("showing") == "true",
+	}
+	for name, dst := range map[string]*int{"year": &query.Year, "page": &query.Page, "per_page": &query.PerPage} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid " + name})
+			return
+		}
+		*dst = n
+	}
+	if strings.TrimSpace(query.Query) == "" && query.Genre == "" && query.Year == 0 && !query.NowShowing {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "q or a filter is required"})
+		return
+	}
+
+	page, err := models.SearchMovies(a.Repositories, query)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (a *app) importMovieHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
 		return
 	}
 	var input struct {
-		Email     string `json:"email"`
-		SessionID int    `json:"session_id"`
-		Seat      string `json:"seat"`
-		IsStudent bool   `json:"is_student"`
-		Age       int    `json:"age"`
+		TMDBID int    `json:"tmdb_id"`
+		Query  string `json:"query"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
 		return
 	}
-	session, ok, err := models.GetSessionByIDMongo(input.SessionID)
+
+	var movie models.Movie
+	var err error
+	switch {
+	case input.TMDBID > 0:
+		movie, err = models.ImportMovie(a.Repositories, input.TMDBID)
+	case strings.TrimSpace(input.Query) != "":
+		movie, err = models.ImportMovieBySearch(a.Repositories, input.Query)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "tmdb_id or query required"})
+		return
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrMovieNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, tmdbErrorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, movie)
+}
+
+func tmdbErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrTMDBNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrTMDBNotConfigured):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, service.ErrTMDBRateLimited):
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadGateway
+}
+
+func (a *app) syncMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
+		return
+	}
+	n, err := models.SyncMovies(a.Repositories)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"synced": n})
+}
+
+func requestedSeats(seat string, seats []string) []string {
+	if len(seats) > 0 {
+		return seats
+	}
+	if seat != "" {
+		return []string{seat}
+	}
+	return nil
+}
+
+func (a *app) createBookingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
+		return
+	}
+	var input struct {
+		Email      string   `json:"email"`
+		SessionID  int      `json:"session_id"`
+		Seat       string   `json:"seat"`
+		Seats      []string `json:"seats"`
+		IsStudent  bool     `json:"is_student"`
+		Age        int      `json:"age"`
+		PromoCode  string   `json:"promo_code"`
+		UseBonuses int      `json:"use_bonuses"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	var promo *models.PromoCode
+	if code := models.NormalizePromoCode(input.PromoCode); code != "" {
+		p, ok, err := a.Promos.Get(code)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": models.ErrPromoNotFound.Error()})
+			return
+		}
+		promo = &p
+	}
+	session, ok, err := a.Sessions.GetByID(input.SessionID)
 	if err != nil || !ok {
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Session not found"})
 		return
 	}
-	if input.Age < 18 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "18+ only"})
+	if session.Status == models.SessionCancelled {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": models.ErrSessionCancelled.Error()})
+		return
+	}
+	if session.MinAge > 0 && input.Age < session.MinAge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%d+ only", session.MinAge)})
+		return
+	}
+	rules, err := models.ActivePricingRules(a.Pricing)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	_, err = models.ReserveSeatMongo(input.SessionID, input.Seat)
+	var hall *models.Hall
+	if session.HallID != 0 {
+		if h, ok, err := a.Halls.GetByID(session.HallID); err == nil && ok {
+			hall = &h
+		}
+	}
+	seats := requestedSeats(input.Seat, input.Seats)
+	owner, _ := r.Context().Value(service.EmailKey).(string)
+	_, holds, err := models.HoldSeats(a.Repositories, input.SessionID, seats, owner, service.SeatHoldTTL())
 	if err != nil {
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 		return
 	}
-	finalPrice := service.CalculatePrice(session.BasePrice, input.IsStudent)
+	releaseHolds := func() {
+		for _, h := range holds {
+			_ = models.ReleaseHold(a.Repositories, h)
+		}
+	}
+	items := make([]models.OrderItem, 0, len(seats))
+	subtotal := 0.0
+	for _, seat := range seats {
+		item := models.QuoteSeat(rules, session, hall, seat, input.Age, input.IsStudent)
+		items = append(items, item)
+		subtotal += item.Price
+	}
+	orderID := primitive.NewObjectID()
+	discount := 0.0
+	appliedPromo := ""
+	if promo != nil {
+		if err := promo.Check(time.Now(), owner, session, subtotal); err != nil {
+			releaseHolds()
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := a.Promos.Redeem(promo.Code, owner, orderID); err != nil {
+			releaseHolds()
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		discount = promo.Discount(subtotal)
+		appliedPromo = promo.Code
+	}
+	finalPrice := subtotal - discount
+	bonusesSpent := 0
+	if input.UseBonuses > 0 {
+		bonusesSpent = input.UseBonuses
+		if float64(bonusesSpent) > finalPrice {
+			bonusesSpent = int(finalPrice)
+		}
+		if bonusesSpent > 0 {
+			if _, err := a.Ledger.Spend(owner, bonusesSpent, orderID); err != nil {
+				releaseHolds()
+				if appliedPromo != "" {
+					_ = a.Promos.Release(appliedPromo, orderID)
+				}
+				writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+				return
+			}
+			finalPrice -= float64(bonusesSpent)
+		}
+	}
+	holdExpiresAt := holds[0].ExpiresAt
 	order := models.Order{
+		ID:            orderID,
 		CustomerEmail: input.Email,
 		MovieTitle:    session.MovieTitle,
 		FinalPrice:    finalPrice,
+		Subtotal:      subtotal,
+		AppliedPromo:  appliedPromo,
+		PromoDiscount: discount,
 		PromoCode:     service.GeneratePromoCode(),
 		BonusesEarned: service.CalcBonuses(finalPrice),
+		BonusesSpent:  bonusesSpent,
+		UserEmail:     owner,
 
 		SessionID:  input.SessionID,
 		CinemaName: session.CinemaName,
 		Hall:       session.Hall,
 		StartTime:  session.StartTime,
-		Seat:       input.Seat,
+		Timezone:   session.Timezone,
+		Seat:       strings.Join(seats, ", "),
+		Items:      items,
 
 		PaymentStatus: "reserved",
+		HoldExpiresAt: holdExpiresAt,
+	}
+	saved, err := a.Orders.Save(order)
+	if err != nil {
+		releaseHolds()
+		if appliedPromo != "" {
+			_ = a.Promos.Release(appliedPromo, orderID)
+		}
+		_ = models.ReverseOrderBonuses(a.Repositories, orderID, "Order could not be saved")
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	for _, h := range holds {
+		attached, err := a.Holds.AttachOrder(h.ID, saved.ID)
+		if err == nil && !attached {
+			err = models.ErrSeatHeld
+		}
+		if err != nil {
+			models.FailOrder(a.Repositories, saved.ID)
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if saved.FinalPrice == 0 {
+		if ok, err := a.Orders.UpdateStatus(saved.ID, "reserved", "paid"); err == nil && ok {
+			saved.PaymentStatus = "paid"
+			if err := models.SellOrderHolds(a.Repositories, saved.ID); err != nil {
+				_, _, _ = models.CancelOrder(a.Repositories, saved, "Seat no longer available")
+				writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+				return
+			}
+		}
 	}
-	saved, _ := models.SaveOrderMongo(order)
 	service.SendAsyncNotification(saved.CustomerEmail, saved.MovieTitle, saved.PromoCode)
-	writeJSON(w, http.StatusCreated, map[string]any{"status": "Success", "order": saved})
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"status":          "Success",
+		"order":           saved,
+		"hold_expires_at": holdExpiresAt,
+	})
 }
 
-func listOrdersHandler(w http.ResponseWriter, r *http.Request) {
-	orders, err := models.GetAllOrdersMongo()
+func (a *app) listOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	orders, err := a.Orders.GetAll()
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	writeJSON(w, http.StatusOK, orders)
+	visible := make([]models.Order, 0, len(orders))
+	for _, o := range orders {
+		if service.CanAccessCinema(r.Context(), service.PermOrdersRead, o.CinemaName) {
+			visible = append(visible, o)
+		}
+	}
+	writeJSON(w, http.StatusOK, visible)
+}
+
+func (a *app) orderItemHandler(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/cancel"):
+		a.cancelOrderHandler(w, r)
+	case strings.HasSuffix(r.URL.Path, "/refund"):
+		service.RequirePermission(service.PermRefundsCreate)(http.HandlerFunc(a.refundOrderHandler)).ServeHTTP(w, r)
+	case strings.HasSuffix(r.URL.Path, "/checkin"):
+		service.RequirePermission(service.PermCheckinScan)(http.HandlerFunc(a.checkInOrderHandler)).ServeHTTP(w, r)
+	case strings.HasSuffix(r.URL.Path, "/exchange"):
+		a.exchangeOrderHandler(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (a *app) orderFromPath(w http.ResponseWriter, r *http.Request, suffix string) (*models.Order, bool) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
+		return nil, false
+	}
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/orders/"), suffix)
+	objID, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid order id"})
+		return nil, false
+	}
+	order, ok, err := a.Orders.GetByID(objID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "order not found"})
+		return nil, false
+	}
+	return order, true
+}
+
+func (a *app) cancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := a.orderFromPath(w, r, "/cancel")
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(service.EmailKey).(string)
+	if !order.OwnedBy(email) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "order not found"})
+		return
+	}
+	if order.SessionChangedAt.IsZero() && !order.StartTime.IsZero() && time.Until(order.StartTime) < service.CancelCutoff() {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "cancellation window has closed"})
+		return
+	}
+	a.cancelOrder(w, order, "Order cancelled")
+}
+
+func (a *app) refundOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := a.orderFromPath(w, r, "/refund")
+	if !ok {
+		return
+	}
+	if !service.CanAccessCinema(r.Context(), service.PermRefundsCreate, order.CinemaName) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "order belongs to another cinema"})
+		return
+	}
+	a.cancelOrder(w, order, "Order refunded")
+}
+
+func (a *app) checkInOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := a.orderFromPath(w, r, "/checkin")
+	if !ok {
+		return
+	}
+	if !service.CanAccessCinema(r.Context(), service.PermCheckinScan, order.CinemaName) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "order belongs to another cinema"})
+		return
+	}
+	staff, _ := r.Context().Value(service.EmailKey).(string)
+	updated, err := models.CheckInOrder(a.Repositories, *order, staff)
+	if err != nil {
+		if errors.Is(err, models.ErrAlreadyCheckedIn) || errors.Is(err, models.ErrNotCheckable) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (a *app) cancelOrder(w http.ResponseWriter, order *models.Order, note string) {
+	updated, refunded, err := models.CancelOrder(a.Repositories, *order, note)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrOrderNotCancellable), errors.Is(err, models.ErrRefundInProgress):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		default:
+			log.Printf("[REFUND] order %s: %v", order.ID.Hex(), err)
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "refund failed: " + err.Error()})
+		}
+		return
+	}
+
+	service.SendRefundNotification(order.CustomerEmail, order.MovieTitle, refunded)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"order":           updated,
+		"refunded_amount": refunded,
+	})
 }
 
-func sessionsHandler(w http.ResponseWriter, r *http.Request) {
+func (a *app) sessionsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodGet {
 		cinema := r.URL.Query().Get("cinema")
+		city := r.URL.Query().Get("city")
 		date := r.URL.Query().Get("date")
 		maxPriceStr := r.URL.Query().Get("max_price")
 		onlyStr := r.URL.Query().Get("only_with_seats")
@@ -204,7 +661,12 @@ func sessionsHandler(w http.ResponseWriter, r *http.Request) {
 			maxPrice, _ = strconv.ParseFloat(maxPriceStr, 64)
 		}
 
-		list, err := models.FilterSessionsMongo(cinema, date, maxPrice, onlyStr == "true")
+		list, err := models.ListSessions(a.Repositories, cinema, city, models.SessionFilter{
+			Date:             date,
+			MaxPrice:         maxPrice,
+			OnlyWithSeats:    onlyStr == "true",
+			IncludeCancelled: r.URL.Query().Get("include_cancelled") == "true",
+		})
 		if err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 			return
@@ -215,15 +677,29 @@ func sessionsHandler(w http.ResponseWriter, r *http.Request) {
 
 	if r.Method == http.MethodPost {
 
-		service.AuthMiddleware(service.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		service.AuthMiddleware(service.RequirePermission(service.PermSessionsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			var s models.Session
 			if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
 				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
 				return
 			}
-			created, err := models.AddSessionMongo(s)
+			cinema := s.CinemaName
+			if s.HallID != 0 {
+				if h, ok, err := a.Halls.GetByID(s.HallID); err == nil && ok {
+					cinema = h.CinemaName
+				}
+			}
+			if !service.CanAccessCinema(r.Context(), service.PermSessionsWrite, cinema) {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "not allowed to manage this cinema"})
+				return
+			}
+			created, err := models.CreateSession(a.Repositories, s)
+			if errors.Is(err, models.ErrScheduleConflict) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+				return
+			}
 			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 				return
 			}
 			writeJSON(w, http.StatusCreated, created)
@@ -232,127 +708,687 @@ func sessionsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "DELETE only"})
+func (a *app) scheduleSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
 		return
 	}
-	idStr := strings.TrimPrefix(r.URL.Path, "/sessions/")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+	var t models.ScheduleTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
 		return
 	}
-	err = models.DeleteSessionMongo(id)
+	if r.URL.Query().Get("dry_run") == "true" {
+		t.DryRun = true
+	}
+	h, ok, err := a.Halls.GetByID(t.HallID)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]string{"message": "Deleted"})
-}
-
-func reserveSeatHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Hall not found"})
 		return
 	}
-	var input struct {
-		SessionID int    `json:"session_id"`
-		Seat      string `json:"seat"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+	if !service.CanAccessCinema(r.Context(), service.PermSessionsWrite, h.CinemaName) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not allowed to manage this cinema"})
 		return
 	}
-	updated, err := models.ReserveSeatMongo(input.SessionID, input.Seat)
-	if err != nil {
+
+	plan, err := models.ApplySchedule(a.Repositories, t)
+	switch {
+	case errors.Is(err, models.ErrScheduleConflict):
+		writeJSON(w, http.StatusConflict, plan)
+	case errors.Is(err, models.ErrInvalidSchedule), errors.Is(err, models.ErrMovieNotFound):
 		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	case plan.DryRun:
+		writeJSON(w, http.StatusOK, plan)
+	default:
+		writeJSON(w, http.StatusCreated, plan)
 	}
-	writeJSON(w, http.StatusOK, updated)
 }
 
-func payInitHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Use POST"})
+func (a *app) sessionItemHandler(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/seatmap") {
+		a.seatMapHandler(w, r)
 		return
 	}
-
-	var in struct {
-		OrderID string `json:"order_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.OrderID == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "order_id is required"})
+	if strings.HasSuffix(r.URL.Path, "/events") {
+		a.seatEventsHandler(w, r)
 		return
 	}
+	service.AuthMiddleware(service.RequirePermission(service.PermSessionsWrite)(http.HandlerFunc(a.sessionChangeHandler))).ServeHTTP(w, r)
+}
 
-	objID, err := primitive.ObjectIDFromHex(in.OrderID)
+func (a *app) seatMapHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET only"})
+		return
+	}
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/sessions/"), "/seatmap")
+	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid order_id format"})
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
 		return
 	}
-
-	order, ok, err := models.GetOrderByIDMongo(objID)
+	session, ok, err := a.Sessions.GetByID(id)
 	if err != nil {
-		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
 	}
 	if !ok {
-		writeJSON(w, 404, map[string]string{"error": "order not found"})
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Session not found"})
 		return
 	}
-
-	invoiceID := makeInvoiceID(order.ID.Hex())
-
-	secretHash, err := service.RandomSecretHash()
+	if session.HallID == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Session has no hall layout"})
+		return
+	}
+	hall, ok, err := a.Halls.GetByID(session.HallID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Hall not found"})
+		return
+	}
+	held, err := a.Holds.ListActiveBySession(id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, models.BuildSeatMap(hall, session, held))
+}
+
+func (a *app) seatEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET only"})
+		return
+	}
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/sessions/"), "/events")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming not supported"})
+		return
+	}
+
+	events, unsubscribe := service.SeatEvents().Subscribe(id)
+	defer unsubscribe()
+	session, ok, err := a.Sessions.GetByID(id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Session not found"})
+		return
+	}
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := writeSSE(w, "snapshot", map[string]any{
+		"session_id":      session.ID,
+		"status":          session.Status,
+		"available_seats": session.AvailableSeats,
+	}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(service.SeatEventsHeartbeat())
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeSSE(w, string(e.Type), e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeSSE(w io.Writer, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+func (a *app) hallsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		halls, err := a.Halls.List(r.URL.Query().Get("cinema"))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, halls)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		service.AuthMiddleware(service.RequirePermission(service.PermHallsWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var h models.Hall
+			if err := json.NewDecoder(r.Body).Decode(&h); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+				return
+			}
+			if !service.CanAccessCinema(r.Context(), service.PermHallsWrite, h.CinemaName) {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "not allowed to manage this cinema"})
+				return
+			}
+			created, err := models.CreateHall(a.Repositories, h)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusCreated, created)
+		}))).ServeHTTP(w, r)
+		return
+	}
+
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET or POST only"})
+}
+
+func (a *app) cinemasHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		cinemas, err := a.Cinemas.List(r.URL.Query().Get("city"))
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		out := make([]models.CinemaDetails, 0, len(cinemas))
+		for _, c := range cinemas {
+			d, err := models.CinemaWithHalls(a.Repositories, c)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			out = append(out, d)
+		}
+		writeJSON(w, http.StatusOK, out)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		service.AuthMiddleware(service.RequirePermission(service.PermCinemasWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var c models.Cinema
+			if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+				return
+			}
+			created, err := models.CreateCinema(a.Repositories, c)
+			if errors.Is(err, models.ErrCinemaExists) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusCreated, created)
+		}))).ServeHTTP(w, r)
+		return
+	}
+
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET or POST only"})
+}
+
+func (a *app) cinemaItemHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/cinemas/")
+	idStr, sub, _ := strings.Cut(rest, "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil || (sub != "" && sub != "halls") {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Cinema not found"})
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		c, ok, err := a.Cinemas.GetByID(id)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "Cinema not found"})
+			return
+		}
+		d, err := models.CinemaWithHalls(a.Repositories, c)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if sub == "halls" {
+			writeJSON(w, http.StatusOK, d.Halls)
+			return
+		}
+		writeJSON(w, http.StatusOK, d)
+		return
+	}
+	if sub != "" {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET only"})
+		return
+	}
+
+	service.AuthMiddleware(service.RequirePermission(service.PermCinemasWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			var c models.Cinema
+			if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+				return
+			}
+			updated, err := models.UpdateCinema(a.Repositories, id, c)
+			if errors.Is(err, models.ErrCinemaNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, models.ErrCinemaLocked) {
+				writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, updated)
+		case http.MethodDelete:
+			err := models.DeleteCinema(a.Repositories, id)
+			switch {
+			case errors.Is(err, models.ErrCinemaNotFound):
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			case errors.Is(err, models.ErrCinemaInUse):
+				writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			case err != nil:
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			default:
+				writeJSON(w, http.StatusOK, map[string]string{"message": "Deleted"})
+			}
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET, PUT or DELETE only"})
+		}
+	}))).ServeHTTP(w, r)
+}
+
+func (a *app) pricingRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		rules, err := a.Pricing.List()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, rules)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		service.AuthMiddleware(service.RequirePermission(service.PermPricingWrite)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var rule service.PricingRule
+			if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+				return
+			}
+			if err := rule.Validate(); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			created, err := a.Pricing.Add(rule)
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusCreated, created)
+		}))).ServeHTTP(w, r)
+		return
+	}
+
+	writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET or POST only"})
+}
+
+func (a *app) pricingRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/pricing/rules/"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var rule service.PricingRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+			return
+		}
+		rule.ID = id
+		if err := rule.Validate(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if err := a.Pricing.Update(rule); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, rule)
+	case http.MethodDelete:
+		if err := a.Pricing.Delete(id); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Deleted"})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "PUT or DELETE only"})
+	}
+}
+
+func (a *app) promosHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		promos, err := a.Promos.List()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, promos)
+	case http.MethodPost:
+		var p models.PromoCode
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+			return
+		}
+		p.Code = models.NormalizePromoCode(p.Code)
+		if err := p.Validate(); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		created, err := a.Promos.Create(p)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET or POST only"})
+	}
+}
+
+func (a *app) promoHandler(w http.ResponseWriter, r *http.Request) {
+	code := models.NormalizePromoCode(strings.TrimPrefix(r.URL.Path, "/promos/"))
+	switch r.Method {
+	case http.MethodGet:
+		p, ok, err := a.Promos.Get(code)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": models.ErrPromoNotFound.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, p)
+	case http.MethodDelete:
+		if err := a.Promos.SetActive(code, false); err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"message": "Deactivated"})
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET or DELETE only"})
+	}
+}
+
+func (a *app) sessionChangeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "PATCH or DELETE only"})
+		return
+	}
+	idStr := strings.TrimPrefix(r.URL.Path, "/sessions/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid ID"})
+		return
+	}
+	session, ok, err := a.Sessions.GetByID(id)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "Session not found"})
+		return
+	}
+	if !service.CanAccessCinema(r.Context(), service.PermSessionsWrite, session.CinemaName) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "not allowed to manage this cinema"})
+		return
+	}
+
+	var impact models.SessionImpact
+	if r.Method == http.MethodPatch {
+		var input models.SessionUpdate
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+			return
+		}
+		impact, err = models.UpdateSession(a.Repositories, id, input)
+	} else {
+		var input struct {
+			Reason string `json:"reason"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+				return
+			}
+		}
+		impact, err = models.CancelSession(a.Repositories, id, strings.TrimSpace(input.Reason))
+	}
+	switch {
+	case errors.Is(err, models.ErrSessionCancelled), errors.Is(err, models.ErrSessionStarted),
+		errors.Is(err, models.ErrSessionChanged), errors.Is(err, models.ErrScheduleConflict):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil && impact.Session.ID != 0:
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": err.Error(), "impact": impact})
+	case err != nil:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, impact)
+	}
+}
+
+func (a *app) exchangeOrderHandler(w http.ResponseWriter, r *http.Request) {
+	order, ok := a.orderFromPath(w, r, "/exchange")
+	if !ok {
+		return
+	}
+	email, _ := r.Context().Value(service.EmailKey).(string)
+	if !order.OwnedBy(email) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "order not found"})
+		return
+	}
+	var input struct {
+		SessionID int      `json:"session_id"`
+		Seats     []string `json:"seats"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	exchanged, err := models.ExchangeOrder(a.Repositories, *order, input.SessionID, input.Seats)
+	if errors.Is(err, models.ErrSeatsNotReturned) {
+		log.Printf("[SESSIONS] exchange of order %s needs manual review: %v", order.ID.Hex(), err)
+		err = nil
+	}
+	switch {
+	case errors.Is(err, models.ErrSessionNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case err != nil:
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, exchanged)
+	}
+}
+
+func (a *app) reserveSeatHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST only"})
+		return
+	}
+	var input struct {
+		SessionID int      `json:"session_id"`
+		Seat      string   `json:"seat"`
+		Seats     []string `json:"seats"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid JSON"})
+		return
+	}
+	owner, _ := r.Context().Value(service.EmailKey).(string)
+	seats := requestedSeats(input.Seat, input.Seats)
+	updated, holds, err := models.HoldSeats(a.Repositories, input.SessionID, seats, owner, service.SeatHoldTTL())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"session":         updated,
+		"holds":           holds,
+		"hold_expires_at": holds[0].ExpiresAt,
+	})
+}
+
+func (a *app) payInitHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "Use POST"})
+		return
+	}
+
+	var in struct {
+		OrderID string `json:"order_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.OrderID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "order_id is required"})
+		return
+	}
+
+	objID, err := primitive.ObjectIDFromHex(in.OrderID)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid order_id format"})
+		return
+	}
+
+	order, ok, err := a.Orders.GetByID(objID)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+	email, _ := r.Context().Value(service.EmailKey).(string)
+	if !ok || !order.OwnedBy(email) {
+		writeJSON(w, 404, map[string]string{"error": "order not found"})
+		return
+	}
+	if order.PaymentStatus != "reserved" || (!order.HoldExpiresAt.IsZero() && order.HoldExpiresAt.Before(time.Now())) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "order is not awaiting payment"})
+		return
+	}
+
+	provider, err := service.PaymentProviderFor(order.CinemaName)
 	if err != nil {
 		writeJSON(w, 500, map[string]string{"error": err.Error()})
 		return
 	}
 
-	auth, err := service.GetEpayToken(invoiceID, order.FinalPrice, "KZT", secretHash)
+	previous, err := a.Payments.ListByOrder(order.ID)
 	if err != nil {
 		writeJSON(w, 500, map[string]string{"error": err.Error()})
 		return
 	}
+	if len(previous) >= maxPaymentAttempts {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "too many payment attempts for this order"})
+		return
+	}
+	invoiceID := makeInvoiceID(order.ID.Hex(), len(previous)+1)
+	currency := service.PaymentCurrency()
 
-	paymentObj, err := service.BuildWidgetPaymentObject(auth, invoiceID, order.FinalPrice, "KZT")
+	session, err := provider.Init(service.PaymentInit{
+		InvoiceID:   invoiceID,
+		OrderID:     order.ID.Hex(),
+		Amount:      order.FinalPrice,
+		Currency:    currency,
+		Description: "CinemaGo booking: " + order.MovieTitle,
+	})
 	if err != nil {
 		writeJSON(w, 500, map[string]string{"error": err.Error()})
 		return
 	}
 
-	_, _ = models.CreatePaymentMongo(models.Payment{
+	_, err = a.Payments.Create(models.Payment{
 		OrderID:    order.ID,
 		InvoiceID:  invoiceID,
 		Amount:     order.FinalPrice,
-		Currency:   "KZT",
-		TerminalID: paymentObj.Terminal,
-		SecretHash: secretHash,
+		Currency:   currency,
+		Provider:   provider.Name(),
+		TerminalID: session.Terminal,
+		SecretHash: session.SecretHash,
 	})
+	if errors.Is(err, models.ErrPaymentExists) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "payment is already being started, try again"})
+		return
+	}
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
 
-	writeJSON(w, 200, map[string]any{
-		"auth":        auth,
-		"payment_obj": paymentObj,
-	})
+	resp := map[string]any{"provider": provider.Name()}
+	for k, v := range session.Client {
+		resp[k] = v
+	}
+	writeJSON(w, 200, resp)
 }
 
-func payCallbackHandler(w http.ResponseWriter, r *http.Request) {
-	body, _ := io.ReadAll(r.Body)
-
-	var cb map[string]any
-	_ = json.Unmarshal(body, &cb)
+func (a *app) payCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	a.handlePaymentCallback(w, r, false)
+}
 
-	invoiceID, _ := cb["invoiceId"].(string)
-	code, _ := cb["code"].(string)
-	epayID, _ := cb["id"].(string)
+func (a *app) handlePaymentCallback(w http.ResponseWriter, r *http.Request, failure bool) {
+	name := strings.Trim(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/pay/callback"), "/pay/failure"), "/")
+	provider, err := service.PaymentProviderByName(name)
+	if err != nil {
+		writeJSON(w, 404, map[string]string{"error": err.Error()})
+		return
+	}
 
-	if invoiceID == "" {
-		writeJSON(w, 400, map[string]string{"error": "missing invoiceId"})
+	body, _ := io.ReadAll(r.Body)
+	cb, err := provider.ParseCallback(body, failure)
+	if err != nil {
+		writeJSON(w, 400, map[string]string{"error": err.Error()})
 		return
 	}
+	invoiceID := cb.InvoiceID
 
-	p, ok, err := models.GetPaymentByInvoiceMongo(invoiceID)
+	p, ok, err := a.Payments.GetByInvoice(invoiceID)
 	if err != nil {
 		writeJSON(w, 500, map[string]string{"error": err.Error()})
 		return
@@ -361,28 +1397,52 @@ func payCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, 404, map[string]string{"error": "payment not found"})
 		return
 	}
+	if p.Provider != "" && p.Provider != provider.Name() {
+		writeJSON(w, 400, map[string]string{"error": "payment belongs to another provider"})
+		return
+	}
 
-	if code == "ok" {
-		_ = models.MarkPaymentPaidMongo(invoiceID, epayID, cb)
-		_ = models.MarkOrderPaidMongo(p.OrderID)
+	if err := provider.VerifyCallback(cb, p.SecretHash, p.Amount); err != nil {
+		log.Printf("[PAY] rejected callback for invoice %s: %v", invoiceID, err)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+
+	if cb.Success {
+		moved, err := models.CompletePayment(a.Repositories, *p, cb.ProviderRef, cb.Raw)
+		if err != nil {
+			writeJSON(w, 500, map[string]string{"error": err.Error()})
+			return
+		}
+		if !moved && p.Status != models.PaymentPaid {
+			log.Printf("[PAY] ignored success callback for invoice %s in status %s", invoiceID, p.Status)
+		}
 	} else {
-		_ = models.MarkPaymentFailedMongo(invoiceID, cb)
+		moved, err := models.FailPayment(a.Repositories, *p, cb.Raw)
+		if err != nil {
+			writeJSON(w, 500, map[string]string{"error": err.Error()})
+			return
+		}
+		if !moved {
+			log.Printf("[PAY] ignored failure callback for invoice %s in status %s", invoiceID, p.Status)
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("OK"))
 }
-func getUserTicketsHandler(w http.ResponseWriter, r *http.Request) {
+
+func (a *app) getUserTicketsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET only"})
 		return
 	}
-	userEmail, ok := r.Context().Value("email").(string)
+	userEmail, ok := r.Context().Value(service.EmailKey).(string)
 	if !ok || userEmail == "" {
 		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "User email not found in context"})
 		return
 	}
-	orders, err := models.GetOrdersByEmailMongo(userEmail)
+	orders, err := a.Orders.GetByEmail(userEmail)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -391,47 +1451,45 @@ func getUserTicketsHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, orders)
 }
 
-func getUserProfileHandler(w http.ResponseWriter, r *http.Request) {
+func (a *app) getUserProfileHandler(w http.ResponseWriter, r *http.Request) {
 	email, _ := r.Context().Value(service.EmailKey).(string)
-	orders, err := models.GetOrdersByEmailMongo(email)
+	orders, err := a.Orders.GetByEmail(email)
 	if err != nil {
 		writeJSON(w, 500, map[string]string{"error": "Cant fetch orders"})
 		return
 	}
 
-	totalBonuses := 0.0
-	for _, o := range orders {
-		totalBonuses += float64(o.BonusesEarned)
+	balance, err := a.Ledger.Balance(email)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": "Cant fetch bonuses"})
+		return
+	}
+	history, err := a.Ledger.History(email)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": "Cant fetch bonuses"})
+		return
 	}
 
 	writeJSON(w, 200, map[string]any{
 		"email":         email,
-		"total_bonuses": totalBonuses,
+		"total_bonuses": balance,
+		"bonus_history": history,
 		"tickets_count": len(orders),
 		"tickets":       orders,
 	})
 }
 
-func payFailureHandler(w http.ResponseWriter, r *http.Request) {
-	body, _ := io.ReadAll(r.Body)
-	var cb map[string]any
-	_ = json.Unmarshal(body, &cb)
-
-	invoiceID, _ := cb["invoiceId"].(string)
-	if invoiceID != "" {
-		_ = models.MarkPaymentFailedMongo(invoiceID, cb)
-	}
-	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("OK"))
+func (a *app) payFailureHandler(w http.ResponseWriter, r *http.Request) {
+	a.handlePaymentCallback(w, r, true)
 }
 
-func payStatusHandler(w http.ResponseWriter, r *http.Request) {
+func (a *app) payStatusHandler(w http.ResponseWriter, r *http.Request) {
 	invoiceID := r.URL.Query().Get("invoice_id")
 	if invoiceID == "" {
 		writeJSON(w, 400, map[string]string{"error": "invoice_id is required"})
 		return
 	}
-	p, ok, err := models.GetPaymentByInvoiceMongo(invoiceID)
+	p, ok, err := a.Payments.GetByInvoice(invoiceID)
 	if err != nil {
 		writeJSON(w, 500, map[string]string{"error": err.Error()})
 		return
@@ -440,21 +1498,100 @@ func payStatusHandler(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, 404, map[string]string{"error": "not found"})
 		return
 	}
-	writeJSON(w, 200, p)
+	order, ok, err := a.Orders.GetByID(p.OrderID)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+	email, _ := r.Context().Value(service.EmailKey).(string)
+	staff := ok && service.CanAccessCinema(r.Context(), service.PermOrdersRead, order.CinemaName)
+	if !ok || (!staff && !order.OwnedBy(email)) {
+		writeJSON(w, 404, map[string]string{"error": "not found"})
+		return
+	}
+	// Stale pending payments are settled by the background reconciler; only
+	// staff may ask the provider directly.
+	if r.URL.Query().Get("refresh") != "true" {
+		writeJSON(w, 200, p)
+		return
+	}
+	if !staff {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "provider status checks are for staff only"})
+		return
+	}
+	provider, err := service.PaymentProviderByName(p.Provider)
+	if err != nil {
+		writeJSON(w, 500, map[string]string{"error": err.Error()})
+		return
+	}
+	remote, err := provider.Status(invoiceID)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, 200, map[string]any{
+		"payment":         p,
+		"provider_status": remote,
+	})
 }
 
-func makeInvoiceID(orderID string) string {
-	s := orderID
-	if len(s) > 15 {
-		s = s[len(s)-15:]
+func (a *app) reconciliationReportsHandler(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	switch r.Method {
+	case http.MethodGet:
+		if date == "" {
+			reports, err := a.Reconciliation.List()
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, reports)
+			return
+		}
+		rep, ok, err := a.Reconciliation.GetByDate(date)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "report not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, rep)
+	case http.MethodPost:
+		day := time.Now().In(service.DefaultLocation()).AddDate(0, 0, -1)
+		if date != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", date, service.DefaultLocation())
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "date must be YYYY-MM-DD"})
+				return
+			}
+			day = parsed
+		}
+		rep, err := models.BuildReconciliationReport(a.Repositories, day)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		saved, err := a.Reconciliation.Save(rep)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, saved)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "GET or POST only"})
 	}
-	return s
 }
 
-func parseDDMMYYYYToISO(date string) string {
-	t, err := time.Parse("02.01.2006", date)
-	if err != nil {
-		return ""
+const maxPaymentAttempts = 999
+
+// Every attempt gets its own invoice, since the provider binds each invoice to
+// the secret issued when it was initialised.
+func makeInvoiceID(orderID string, attempt int) string {
+	s := orderID
+	if len(s) > 12 {
+		s = s[len(s)-12:]
 	}
-	return t.Format("2006-01-02")
+	return fmt.Sprintf("%s%03d", s, attempt)
 }
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"cinema/internal/models"
+	"cinema/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const sandboxCustomer = "buyer@example.com"
+
+// newSandboxServer wires the payment handlers and the local ePay sandbox onto
+// one test server, the same way main does when EPAY_ENV=local.
+func newSandboxServer(t *testing.T) (*app, *httptest.Server) {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("EPAY_ENV", "local")
+	t.Setenv("EPAY_SANDBOX_DELAY", "50ms")
+	service.InitJWT()
+
+	a := &app{Repositories: models.NewMemoryRepositories()}
+	mux := http.NewServeMux()
+	mux.Handle("/pay/init", service.AuthMiddleware(http.HandlerFunc(a.payInitHandler)))
+	mux.HandleFunc("/pay/callback", a.payCallbackHandler)
+	mux.HandleFunc("/pay/failure", a.payFailureHandler)
+	mux.Handle(service.EpaySandboxPrefix+"/", service.NewEpaySandbox())
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	t.Setenv("APP_BASE_URL", srv.URL)
+	return a, srv
+}
+
+// payWithSandbox creates a reserved order, starts a payment for it and submits
+// the sandbox form with the given outcome.
+func payWithSandbox(t *testing.T, a *app, srv *httptest.Server, outcome string) (primitive.ObjectID, string) {
+	t.Helper()
+	order, err := a.Orders.Save(models.Order{
+		ID:            primitive.NewObjectID(),
+		UserEmail:     sandboxCustomer,
+		CustomerEmail: sandboxCustomer,
+		MovieTitle:    "Sandbox",
+		FinalPrice:    1500,
+		BonusesEarned: 75,
+		PaymentStatus: "reserved",
+		StartTime:     time.Now().Add(24 * time.Hour),
+		HoldExpiresAt: time.Now().Add(10 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("save order: %v", err)
+	}
+
+	token, err := service.GenerateJWT(sandboxCustomer, sandboxCustomer, "user", nil, 0, false)
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	body, _ := json.Marshal(map[string]string{"order_id": order.ID.Hex()})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/pay/init", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("pay init: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("pay init status = %d", resp.StatusCode)
+	}
+	var init struct {
+		PaymentObj service.EpayWidgetPaymentObject `json:"payment_obj"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&init); err != nil {
+		t.Fatalf("decode pay init: %v", err)
+	}
+
+	obj, _ := json.Marshal(init.PaymentObj)
+	reg, err := http.Post(srv.URL+service.EpaySandboxPrefix+"/invoices", "application/json", bytes.NewReader(obj))
+	if err != nil {
+		t.Fatalf("register invoice: %v", err)
+	}
+	reg.Body.Close()
+	if reg.StatusCode != http.StatusOK {
+		t.Fatalf("register invoice status = %d", reg.StatusCode)
+	}
+
+	form := url.Values{"invoiceId": {init.PaymentObj.InvoiceId}, "outcome": {outcome}}
+	pay, err := http.PostForm(srv.URL+service.EpaySandboxPrefix+"/pay?redirect=false", form)
+	if err != nil {
+		t.Fatalf("sandbox pay: %v", err)
+	}
+	pay.Body.Close()
+	if pay.StatusCode != http.StatusOK {
+		t.Fatalf("sandbox pay status = %d", pay.StatusCode)
+	}
+	return order.ID, init.PaymentObj.InvoiceId
+}
+
+func sandboxState(t *testing.T, a *app, orderID primitive.ObjectID, invoiceID string) (string, models.PaymentStatus) {
+	t.Helper()
+	o, ok, err := a.Orders.GetByID(orderID)
+	if err != nil || !ok {
+		t.Fatalf("get order: ok=%v err=%v", ok, err)
+	}
+	p, ok, err := a.Payments.GetByInvoice(invoiceID)
+	if err != nil || !ok {
+		t.Fatalf("get payment: ok=%v err=%v", ok, err)
+	}
+	return o.PaymentStatus, p.Status
+}
+
+func earnedEntries(t *testing.T, a *app, orderID primitive.ObjectID) int {
+	t.Helper()
+	entries, err := a.Ledger.ListByOrder(orderID)
+	if err != nil {
+		t.Fatalf("ledger: %v", err)
+	}
+	n := 0
+	for _, e := range entries {
+		if e.Type == models.LedgerEarn {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSandboxApprove(t *testing.T) {
+	a, srv := newSandboxServer(t)
+	orderID, invoiceID := payWithSandbox(t, a, srv, service.SandboxApprove)
+
+	order, payment := sandboxState(t, a, orderID, invoiceID)
+	if order != "paid" || payment != models.PaymentPaid {
+		t.Fatalf("order=%s payment=%s, want paid/paid", order, payment)
+	}
+	if n := earnedEntries(t, a, orderID); n != 1 {
+		t.Fatalf("earn entries = %d, want 1", n)
+	}
+}
+
+func TestSandboxDecline(t *testing.T) {
+	a, srv := newSandboxServer(t)
+	orderID, invoiceID := payWithSandbox(t, a, srv, service.SandboxDecline)
+
+	order, payment := sandboxState(t, a, orderID, invoiceID)
+	if order != "failed" || payment != models.PaymentFailed {
+		t.Fatalf("order=%s payment=%s, want failed/failed", order, payment)
+	}
+	if n := earnedEntries(t, a, orderID); n != 0 {
+		t.Fatalf("earn entries = %d, want 0", n)
+	}
+}
+
+func TestSandboxDuplicateCallback(t *testing.T) {
+	a, srv := newSandboxServer(t)
+	orderID, invoiceID := payWithSandbox(t, a, srv, service.SandboxDuplicate)
+
+	order, payment := sandboxState(t, a, orderID, invoiceID)
+	if order != "paid" || payment != models.PaymentPaid {
+		t.Fatalf("order=%s payment=%s, want paid/paid", order, payment)
+	}
+	if n := earnedEntries(t, a, orderID); n != 1 {
+		t.Fatalf("earn entries = %d after duplicate callback, want 1", n)
+	}
+}
+
+func TestSandboxDelayedCallback(t *testing.T) {
+	a, srv := newSandboxServer(t)
+	orderID, invoiceID := payWithSandbox(t, a, srv, service.SandboxDelayed)
+
+	order, payment := sandboxState(t, a, orderID, invoiceID)
+	if order != "reserved" || payment != models.PaymentPending {
+		t.Fatalf("before callback: order=%s payment=%s, want reserved/pending", order, payment)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if order, payment = sandboxState(t, a, orderID, invoiceID); payment != models.PaymentPending {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if order != "paid" || payment != models.PaymentPaid {
+		t.Fatalf("after callback: order=%s payment=%s, want paid/paid", order, payment)
+	}
+}
+
+func TestPayInitIgnoresContactEmail(t *testing.T) {
+	a, srv := newSandboxServer(t)
+	order, err := a.Orders.Save(models.Order{
+		ID:            primitive.NewObjectID(),
+		UserEmail:     sandboxCustomer,
+		CustomerEmail: "someone-else@example.com",
+		FinalPrice:    1500,
+		PaymentStatus: "reserved",
+		HoldExpiresAt: time.Now().Add(10 * time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("save order: %v", err)
+	}
+
+	token, _ := service.GenerateJWT("someone-else@example.com", "someone-else@example.com", "user", nil, 0, false)
+	body, _ := json.Marshal(map[string]string{"order_id": order.ID.Hex()})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/pay/init", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("pay init: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("pay init by contact email = %d, want 404", resp.StatusCode)
+	}
+}
@@ -6,7 +6,7 @@
     }
     try {
         const payload = JSON.parse(atob(token.split('.')[1]));
-        if (payload.role !== 'admin') {
+        if (!['admin', 'super_admin', 'cinema_manager', 'cashier', 'usher'].includes(payload.role)) {
             window.location.href = "/";
         }
     } catch (e) {
@@ -31,6 +31,7 @@ document.addEventListener('DOMContentLoaded', loadAdminData);
 async function loadAdminData() {
     console.log("🔄 Синхронизация с MongoDB...");
     await Promise.all([
+        loadMovieCatalog(),
         loadStatistics(),
         loadSessionsForAdmin(),
         loadOrdersForAdmin()
@@ -54,9 +55,10 @@ async function loadStatistics() {
         const totalSessionsEl = document.getElementById('totalSessions');
         if(totalSessionsEl) totalSessionsEl.textContent = sessions.length;
 
-        const uniqueMovies = [...new Set(sessions.map(s => s.movie_title))];
+        const moviesRes = await fetch('/movies');
+        const movies = moviesRes.ok ? await moviesRes.json() : [];
         const totalMoviesEl = document.getElementById('totalMovies');
-        if(totalMoviesEl) totalMoviesEl.textContent = uniqueMovies.length;
+        if(totalMoviesEl) totalMoviesEl.textContent = movies.length;
 
     } catch (error) {
         console.error('Error loading statistics:', error);
@@ -65,7 +67,7 @@ async function loadStatistics() {
 
 async function loadSessionsForAdmin() {
     try {
-        const res = await fetch(`/sessions?date=all`);
+        const res = await fetch(`/sessions?date=all&include_cancelled=true`);
         const sessions = await res.json();
         renderAdminSessions(sessions);
     } catch (error) {
@@ -91,7 +93,8 @@ function renderAdminSessions(sessions) {
     container.innerHTML = sortedSessions.map(session => {
         const dateVal = session.start_time?.$date || session.start_time;
         const formattedDate = new Date(dateVal).toLocaleString('ru-RU', {
-            hour: '2-digit', minute: '2-digit', day: '2-digit', month: 'short', year: 'numeric'
+            hour: '2-digit', minute: '2-digit', day: '2-digit', month: 'short', year: 'numeric',
+            timeZone: session.timezone || undefined
         });
 
         return `
@@ -101,7 +104,9 @@ function renderAdminSessions(sessions) {
                 <td>${formattedDate}</td>
                 <td><span class="price">${(session.base_price || 0).toLocaleString()} ₸</span></td>
                 <td>
-                    <button onclick="deleteSession(${session.id})" class="btn-delete">Delete</button>
+                    ${session.status === 'cancelled'
+                        ? '<small class="badge">Cancelled</small>'
+                        : `<button onclick="deleteSession(${session.id})" class="btn-delete">Cancel</button>`}
                 </td>
             </tr>
         `;
@@ -140,8 +145,38 @@ function renderAdminOrders(orders) {
     `).join('');
 }
 
+async function loadMovieCatalog() {
+    const select = document.getElementById('movieId');
+    if (!select) return;
+    try {
+        const res = await fetch('/movies');
+        const movies = res.ok ? await res.json() : [];
+        select.innerHTML = movies.length
+            ? movies.map(m => `<option value="${m.id}">${m.title}${m.release_date ? ` (${m.release_date.slice(0, 4)})` : ''}</option>`).join('')
+            : '<option value="">Import a movie first</option>';
+    } catch (error) {
+        console.error('Error loading movie catalog:', error);
+    }
+}
+
+async function importMovie() {
+    const input = document.getElementById('movieImport');
+    const value = input.value.trim();
+    if (!value) return;
+    const body = /^\d+$/.test(value) ? { tmdb_id: parseInt(value) } : { query: value };
+    const res = await authFetch('/movies/import', { method: 'POST', body: JSON.stringify(body) });
+    const data = await res.json();
+    if (!res.ok) {
+        alert(data.error || 'Import failed');
+        return;
+    }
+    input.value = '';
+    await loadMovieCatalog();
+    document.getElementById('movieId').value = data.id;
+}
+
 async function createSession() {
-    const movieTitle = document.getElementById('movieTitle').value.trim();
+    const movieId = parseInt(document.getElementById('movieId').value);
     const cinemaName = document.getElementById('cinemaName').value;
     const hall = document.getElementById('hall').value.trim();
     const startTime = document.getElementById('startTime').value;
@@ -164,19 +199,18 @@ async function createSession() {
         generatedSeats.push(`${rows[rowIdx]}${seatNum}`);
     }
 
-    if (!movieTitle || !startTime || !basePrice) {
+    if (!movieId || !startTime || !basePrice) {
         alert('Please fill all required fields');
         return;
     }
 
     const payload = {
-        movie_title: movieTitle,
         cinema_name: cinemaName,
         hall: hall || "hall 1",
         start_time: new Date(startTime).toISOString(),
         base_price: basePrice,
         available_seats: generatedSeats,
-        movie_id: 0
+        movie_id: movieId
     };
 
     console.log("📤 Отправка в MongoDB:", payload);
@@ -190,7 +224,6 @@ async function createSession() {
         if (response.ok) {
             alert(`✨ Success! Session created with ${generatedSeats.length} seats.`);
             loadAdminData();
-            document.getElementById('movieTitle').value = '';
             document.getElementById('startTime').value = '';
             document.getElementById('hall').value = '';
         } else {
@@ -203,19 +236,39 @@ async function createSession() {
 }
 
 async function deleteSession(sessionId) {
-    if (!confirm(`Delete session #${sessionId}?`)) return;
+    if (!confirm(`Cancel session #${sessionId}? Sold tickets will be refunded.`)) return;
+    const reason = prompt("Reason shown to customers (optional):") || "";
     try {
-        const res = await authFetch(`/sessions/${sessionId}`, { method: 'DELETE' });
+        const res = await authFetch(`/sessions/${sessionId}`, {
+            method: 'DELETE',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ reason })
+        });
+        const data = await res.json();
         if (res.ok) {
-            alert("Session deleted.");
+            alert(`Session cancelled. Orders refunded: ${data.refunded || 0}, customers notified: ${data.notified}.`);
             loadAdminData();
+        } else {
+            alert(data.error || "Failed to cancel session");
         }
     } catch (error) {
         console.error(error);
     }
 }
 
-window.logout = function() {
+window.logout = async function() {
+    const token = localStorage.getItem("token");
+    if (token) {
+        try {
+            await fetch("/logout", {
+                method: "POST",
+                headers: { "Content-Type": "application/json", "Authorization": `Bearer ${token}` },
+                body: JSON.stringify({ refresh_token: localStorage.getItem("refresh_token") || "" })
+            });
+        } catch (e) {
+            console.error(e);
+        }
+    }
     localStorage.clear();
     window.location.href = "/pages/auth.html";
 };
\ No newline at end of file
@@ -44,7 +44,7 @@ function isValidEmail(email) {
     return /^[^\s@]+@[^\s@]+\.[^\s@]+$/.test(email);
 }
 
-function formatDateTime(dateString) {
+function formatDateTime(dateString, timeZone) {
     const date = new Date(dateString);
     return date.toLocaleString('en-US', {
         weekday: 'short',
@@ -52,7 +52,8 @@ function formatDateTime(dateString) {
         month: 'short',
         day: 'numeric',
         hour: '2-digit',
-        minute: '2-digit'
+        minute: '2-digit',
+        timeZone: timeZone || undefined
     });
 }
 
@@ -70,3 +71,25 @@ function authFetch(url, options = {}) {
     }
   });
 }
+
+async function loadCinemaOptions() {
+  const selects = document.querySelectorAll("select[data-cinemas]");
+  if (selects.length === 0) return;
+  try {
+    const res = await fetch("/cinemas");
+    if (!res.ok) return;
+    const cinemas = await res.json();
+    selects.forEach(select => {
+      cinemas.forEach(c => {
+        const option = document.createElement("option");
+        option.value = c.name;
+        option.textContent = c.city ? `${c.name} (${c.city})` : c.name;
+        select.appendChild(option);
+      });
+    });
+  } catch (e) {
+    console.error("Failed to load cinemas", e);
+  }
+}
+
+document.addEventListener("DOMContentLoaded", loadCinemaOptions);
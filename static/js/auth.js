@@ -44,6 +44,35 @@ function getRoleFromToken(token) {
   }
 }
 
+async function completeTwoFactorLogin(mfaToken) {
+  const code = prompt("Enter the 6-digit code from your authenticator app or a recovery code");
+  if (!code) return null;
+  const res = await fetch("/auth/2fa/verify", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ mfa_token: mfaToken, code: code.trim() })
+  });
+  return res.ok ? res.json() : null;
+}
+
+async function enrollTwoFactor(data) {
+  const headers = { "Content-Type": "application/json", "Authorization": "Bearer " + data.token };
+  const setup = await fetch("/auth/2fa/setup", { method: "POST", headers });
+  if (!setup.ok) return null;
+  const { secret, otpauth_uri } = await setup.json();
+  const code = prompt(
+    "Two-factor authentication is required for staff accounts.\n" +
+    "Add this key to your authenticator app:\n" + secret + "\n\n" +
+    "(or open " + otpauth_uri + ")\n\nThen enter the 6-digit code:"
+  );
+  if (!code) return null;
+  const res = await fetch("/auth/2fa/enable", { method: "POST", headers, body: JSON.stringify({ code: code.trim() }) });
+  if (!res.ok) return null;
+  const enabled = await res.json();
+  alert("Save these recovery codes somewhere safe:\n\n" + enabled.recovery_codes.join("\n"));
+  return { ...data, ...enabled, mfa_setup_required: false };
+}
+
 document.addEventListener("DOMContentLoaded", () => {
   console.log("auth.js ready");
 
@@ -55,6 +84,10 @@ document.addEventListener("DOMContentLoaded", () => {
   const loginBtn = document.getElementById("loginBtn");
   const registerBtn = document.getElementById("registerBtn");
 
+  const emailStatus = new URLSearchParams(window.location.search).get("email");
+  if (statusEl && emailStatus === "verified") statusEl.textContent = "Email confirmed. You can sign in now.";
+  if (statusEl && emailStatus === "invalid") statusEl.textContent = "Verification link is invalid or expired.";
+
   if (loginTab && registerTab) {
     loginTab.onclick = () => {
       loginForm.style.display = "block";
@@ -108,15 +141,28 @@ document.addEventListener("DOMContentLoaded", () => {
           })
         });
 
-        const data = await res.json();
+        let data = await res.json();
+
+        if (res.ok && data.mfa_required) {
+          data = await completeTwoFactorLogin(data.mfa_token);
+          if (!data) {
+            statusEl.textContent = "Two-factor verification failed";
+            return;
+          }
+        }
+
+        if (res.ok && data.token && data.mfa_setup_required) {
+          data = (await enrollTwoFactor(data)) || data;
+        }
 
         if (res.ok && data.token) {
           localStorage.clear();
           localStorage.setItem("token", data.token);
+          if (data.refresh_token) localStorage.setItem("refresh_token", data.refresh_token);
 
           const userRole = data.role || getRoleFromToken(data.token);
 
-          if (userRole === "admin") {
+          if (["admin", "super_admin", "cinema_manager", "cashier", "usher"].includes(userRole)) {
             window.location.href = "/pages/admin.html";
           } else {
             window.location.href = "/";
@@ -206,7 +252,7 @@ function checkAuth() {
         });
         
         const payload = parseJwt(token);
-        if (payload && payload.role === "admin" && adminNavLink) {
+        if (payload && ["admin", "super_admin", "cinema_manager", "cashier", "usher"].includes(payload.role) && adminNavLink) {
             adminNavLink.style.display = "inline-block";
         }
     } else {
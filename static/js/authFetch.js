@@ -1,4 +1,26 @@
-async function authFetch(url, options = {}) {
+async function refreshAccessToken() {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) return false;
+
+    try {
+        const res = await fetch('/auth/refresh', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken })
+        });
+        if (!res.ok) return false;
+
+        const data = await res.json();
+        localStorage.setItem('token', data.token);
+        localStorage.setItem('refresh_token', data.refresh_token);
+        return true;
+    } catch (error) {
+        console.error("Token refresh failed:", error);
+        return false;
+    }
+}
+
+async function authFetch(url, options = {}, retried = false) {
     const token = localStorage.getItem('token');
 
     if (!token) {
@@ -19,9 +41,14 @@ async function authFetch(url, options = {}) {
     try {
         const response = await fetch(url, fetchOptions);
 
+        if (response.status === 401 && !retried && await refreshAccessToken()) {
+            return authFetch(url, options, true);
+        }
+
         if (response.status === 401 || response.status === 403) {
             console.error("Session expired or unauthorized. Clearing storage...");
             localStorage.removeItem('token');
+            localStorage.removeItem('refresh_token');
             window.location.href = '/pages/auth.html';
             return Promise.reject('Session expired');
         }
@@ -31,4 +58,4 @@ async function authFetch(url, options = {}) {
         console.error("Network error in authFetch:", error);
         throw error;
     }
-}
\ No newline at end of file
+}
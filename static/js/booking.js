@@ -17,6 +17,7 @@ function authFetch(url, options = {}) {
 }
 
 let selectedSessionData = null;
+let seatEvents = null;
 
 function formatPrice(price) { return price + " ₸"; }
 
@@ -70,7 +71,6 @@ async function loadSelectedSession() {
 
 async function fetchAndRenderSeats() {
   const container = document.getElementById("availableSeats");
-  const seatInput = document.getElementById("seat");
   if (!container || !selectedSessionData) return;
 
   try {
@@ -108,25 +108,10 @@ async function fetchAndRenderSeats() {
 
     const createSeat = (rowLetter, seatNum, originalId) => {
       const seatEl = document.createElement("div");
-      const isTaken = !availableList.includes(originalId);
-      const visualName = `${rowLetter}${seatNum}`;
-
-      seatEl.textContent = visualName;
-
-      if (isTaken) {
-        seatEl.className = "seat-node occupied";
-      } else {
-        seatEl.className = "seat-node free";
-        seatEl.onclick = () => {
-          document.querySelectorAll(".seat-node.selected").forEach((s) => s.classList.remove("selected"));
-          seatEl.classList.add("selected");
-
-          if (seatInput) {
-            seatInput.value = visualName;
-            seatInput.dataset.originalId = originalId;
-          }
-        };
-      }
+      seatEl.textContent = `${rowLetter}${seatNum}`;
+      seatEl.className = "seat-node";
+      seatEl.dataset.seat = originalId;
+      setSeatState(seatEl, !availableList.includes(originalId));
       return seatEl;
     };
 
@@ -172,11 +157,59 @@ async function fetchAndRenderSeats() {
     }
 
     container.appendChild(hallContainer);
+    subscribeSeatEvents(dbSession.id);
   } catch (e) {
     console.error("Error rendering seats:", e);
   }
 }
 
+function setSeatState(seatEl, taken) {
+  if (seatEl.classList.contains(taken ? "occupied" : "free")) return;
+  const seatInput = document.getElementById("seat");
+  seatEl.classList.remove("free", "occupied", "selected");
+
+  if (taken) {
+    seatEl.classList.add("occupied");
+    seatEl.onclick = null;
+    if (seatInput && seatInput.dataset.originalId === seatEl.dataset.seat) {
+      seatInput.value = "";
+      delete seatInput.dataset.originalId;
+    }
+    return;
+  }
+
+  seatEl.classList.add("free");
+  seatEl.onclick = () => {
+    document.querySelectorAll(".seat-node.selected").forEach((s) => s.classList.remove("selected"));
+    seatEl.classList.add("selected");
+
+    if (seatInput) {
+      seatInput.value = seatEl.textContent;
+      seatInput.dataset.originalId = seatEl.dataset.seat;
+    }
+  };
+}
+
+function subscribeSeatEvents(sessionId) {
+  if (seatEvents || !window.EventSource) return;
+  seatEvents = new EventSource(`/sessions/${sessionId}/events`);
+
+  const updateSeat = (taken) => (e) => {
+    const data = JSON.parse(e.data);
+    const seatEl = document.querySelector(`.seat-node[data-seat="${data.seat}"]`);
+    if (seatEl) setSeatState(seatEl, taken);
+  };
+  seatEvents.addEventListener("seat_held", updateSeat(true));
+  seatEvents.addEventListener("seat_sold", updateSeat(true));
+  seatEvents.addEventListener("seat_released", updateSeat(false));
+  seatEvents.addEventListener("snapshot", (e) => {
+    const available = JSON.parse(e.data).available_seats || [];
+    document.querySelectorAll(".seat-node[data-seat]").forEach((seatEl) => {
+      setSeatState(seatEl, !available.includes(seatEl.dataset.seat));
+    });
+  });
+}
+
 
 function updatePriceCalculation() {
   if (!selectedSessionData) return;
@@ -200,8 +233,8 @@ function loadScriptOnce(src) {
   });
 }
 
-async function openHalykPaymentWidget(auth, paymentObj) {
-  const widgetSrc = "https://test-epay.epayment.kz/payform/payment-api.js"; 
+async function openHalykPaymentWidget(auth, paymentObj, widgetUrl) {
+  const widgetSrc = widgetUrl || "https://test-epay.epayment.kz/payform/payment-api.js";
 
   await loadScriptOnce(widgetSrc);
 
@@ -271,7 +304,7 @@ async function bookTicket() {
       return;
     }
 
-    await openHalykPaymentWidget(payData.auth, payData.payment_obj);
+    await openHalykPaymentWidget(payData.auth, payData.payment_obj, payData.widget_url);
 
   } catch (e) {
     console.error(e);
@@ -202,20 +202,22 @@ async function liveSearch(query) {
     }
 
     try {
-        const res = await fetch(`/movies?title=${encodeURIComponent(query)}`);
-        const movie = await res.json();
-        if (movie && movie.title) {
-            box.innerHTML = `
-                <div class="suggestion-item" onclick="selectSuggestion('${movie.id}')">
-                    <img src="https://image.tmdb.org/t/p/w92${movie.poster_path}" alt="">
-                    <div class="suggestion-info">
-                        <span class="suggestion-title">${movie.title}</span>
-                        <span class="suggestion-year">${movie.release_date ? movie.release_date.split('-')[0] : ''}</span>
-                    </div>
-                </div>
-            `;
-            box.style.display = 'block';
+        const res = await fetch(`/movies/search?q=${encodeURIComponent(query)}&per_page=5`);
+        const page = await res.json();
+        if (!res.ok || !page.results || page.results.length === 0) {
+            box.style.display = 'none';
+            return;
         }
+        box.innerHTML = page.results.map(movie => `
+            <div class="suggestion-item" onclick="selectSuggestion('${movie.id}')">
+                <img src="https://image.tmdb.org/t/p/w92${movie.poster_path}" alt="">
+                <div class="suggestion-info">
+                    <span class="suggestion-title">${movie.title}</span>
+                    <span class="suggestion-year">${movie.release_date ? movie.release_date.split('-')[0] : ''}${movie.upcoming_sessions > 0 ? ' · Now showing' : ''}</span>
+                </div>
+            </div>
+        `).join('');
+        box.style.display = 'block';
     } catch (e) {
         console.error("Live search error", e);
     }
@@ -25,7 +25,7 @@ document.addEventListener("DOMContentLoaded", () => {
 
     console.log("🛠️ Role detected:", userRole);
 
-    if (userRole === "admin") {
+    if (["admin", "super_admin", "cinema_manager", "cashier", "usher"].includes(userRole)) {
       const isRoot = currentPath === "/" || currentPath === "/index.html";
       const isSearchPage = currentPath.includes("/pages/index.html") || currentPath.includes("/static/pages/index.html");
 
@@ -78,7 +78,19 @@ function highlightActiveLink() {
 }
 
 window.goLogin = function() { window.location.href = "/pages/auth.html"; };
-window.logout = function() {
+window.logout = async function() {
+  const token = localStorage.getItem("token");
+  if (token) {
+    try {
+      await fetch("/logout", {
+        method: "POST",
+        headers: { "Content-Type": "application/json", "Authorization": `Bearer ${token}` },
+        body: JSON.stringify({ refresh_token: localStorage.getItem("refresh_token") || "" })
+      });
+    } catch (e) {
+      console.error(e);
+    }
+  }
   localStorage.clear();
   window.location.href = "/pages/auth.html";
 };
\ No newline at end of file
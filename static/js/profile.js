@@ -40,7 +40,7 @@ async function loadUserTickets() {
   const status = t.payment_status || 'reserved';
   const isPaid = status === 'paid';
 
-  const dateStr = t.start_time ? new Date(t.start_time).toLocaleString() : '—';
+  const dateStr = t.start_time ? new Date(t.start_time).toLocaleString([], { timeZone: t.timezone || undefined }) : '—';
 
   return `
   <div class="session-card" style="margin-bottom: 15px; justify-content: space-between;">
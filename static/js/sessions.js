@@ -105,7 +105,7 @@ function renderSessions(sessions) {
                     </div>
                     <div class="grid-item">
                         <span class="icon">🕒</span>
-                        <span class="text">${formatDateTime(session.start_time)}</span>
+                        <span class="text">${formatDateTime(session.start_time, session.timezone)}</span>
                     </div>
                     <div class="grid-item">
                         <span class="icon">💰</span>